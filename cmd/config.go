@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var configSlug string
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Operations with configuration",
+	Long: `Operations with configuration: show and validate.
+Secrets (telegram.bot_token, telegram.app_hash, database.dsn) can be set in environment variables,
+e.g. TG_TELEGRAM_BOT_TOKEN, or read from files, e.g. telegram.bot_token_file: /run/secrets/bot_token.
+Bot settings can be stored in tg_config table, use --slug to merge them into configuration.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if configSlug == "" {
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			fmt.Printf("connect to database: %s\n", err)
+			os.Exit(1)
+		}
+		defer d.Close()
+
+		if _, err := loadBotSettings(context.Background(), d, configSlug); err != nil {
+			fmt.Printf("load bot settings: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print effective configuration with masked secrets",
+	Long: `Print effective configuration merged from config file, environment, flags and defaults.
+Secrets are masked, durations are printed as duration strings, e.g. 15m0s.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+
+		if err := enc.Encode(cfg.Redacted()); err != nil {
+			fmt.Printf("print config: %s\n", err)
+		}
+	},
+}
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check configuration",
+	Long: `Check that credentials are set, sizes and intervals are positive,
+storage.audio directory exists and delivery policy is valid.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cfg.Validate(); err != nil {
+			fmt.Printf("config is invalid:\n%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("config is valid")
+	},
+}
+
+// loadBotSettings merges bot settings from tg_config with slug into cfg,
+// they override config file, see config.Config.WithBotSettings.
+func loadBotSettings(ctx context.Context, d database.Tgdb, slug string) (*domain.Config, error) {
+	c, err := d.GetConfig(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.WithBotSettings(c.Settings)
+	return c, nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+	configCmd.PersistentFlags().StringVar(&configSlug, "slug", "", "Slug of tg_config to merge bot settings from database.")
+}
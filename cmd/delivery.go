@@ -0,0 +1,161 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/delivery"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+var (
+	dripPerHour int
+	dripWindow  string
+	dripSilent  bool
+	dripOff     bool
+	quietHours  string
+	quietMode   string
+	quietOff    bool
+	timezone    string
+	resetPolicy bool
+)
+
+// deliveryCmd represents the topics delivery command
+var deliveryCmd = &cobra.Command{
+	Use:   "delivery <topic id>",
+	Short: "Show or change delivery policy of topic",
+	Long: `Show or change delivery policy of topic. Topic policy overrides global "delivery" section of config file.
+Drip mode limits number of posts per hour and publishes only within daily time window,
+it's useful to backfill archives without flooding subscribers:
+
+  tg topics delivery 3 --drip-per-hour 2 --drip-window 09:00-21:00 --drip-silent --timezone Europe/Moscow
+
+Quiet hours send messages without notification or defer them until morning:
+
+  tg topics delivery 3 --quiet-hours 22:00-08:00 --quiet-mode defer`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			log.Error().Err(err).Str("topic", args[0]).Msg("parse topic id")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		topics, err := d.ListAllTopics(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("list all topics")
+			return
+		}
+
+		var topic *domain.Topic
+		for i := range topics {
+			if topics[i].ID == id {
+				topic = &topics[i]
+				break
+			}
+		}
+		if topic == nil {
+			log.Error().Uint64("topic", id).Msg("topic not found")
+			return
+		}
+
+		modified := false
+		for _, f := range []string{"drip-per-hour", "drip-window", "drip-silent", "drip-off", "quiet-hours", "quiet-mode", "quiet-off", "timezone", "reset"} {
+			modified = modified || cmd.Flags().Changed(f)
+		}
+
+		p := topic.Delivery
+		if resetPolicy {
+			p = domain.DeliveryPolicy{}
+		}
+		if cmd.Flags().Changed("timezone") {
+			p.Timezone = timezone
+		}
+		if dripOff {
+			p.Drip = nil
+		}
+		if cmd.Flags().Changed("drip-per-hour") || cmd.Flags().Changed("drip-window") || cmd.Flags().Changed("drip-silent") {
+			drip := domain.DripPolicy{}
+			if p.Drip != nil {
+				drip = *p.Drip
+			}
+			if cmd.Flags().Changed("drip-per-hour") {
+				drip.PostsPerHour = dripPerHour
+			}
+			if cmd.Flags().Changed("drip-window") {
+				drip.Window = dripWindow
+			}
+			if cmd.Flags().Changed("drip-silent") {
+				drip.Silent = dripSilent
+			}
+			p.Drip = &drip
+		}
+		if quietOff {
+			p.Quiet = nil
+		}
+		if cmd.Flags().Changed("quiet-hours") || cmd.Flags().Changed("quiet-mode") {
+			quiet := domain.QuietHours{}
+			if p.Quiet != nil {
+				quiet = *p.Quiet
+			}
+			if cmd.Flags().Changed("quiet-hours") {
+				quiet.Window = quietHours
+			}
+			if cmd.Flags().Changed("quiet-mode") {
+				quiet.Mode = quietMode
+			}
+			p.Quiet = &quiet
+		}
+
+		if err := delivery.Validate(p); err != nil {
+			log.Error().Err(err).Msg("invalid delivery policy")
+			return
+		}
+
+		if modified {
+			if err := d.SetTopicDelivery(ctx, topic.ID, p); err != nil {
+				log.Error().Err(err).Msg("save delivery policy")
+				return
+			}
+			log.Info().Uint64("topic", topic.ID).Str("name", topic.Name).Msg("delivery policy updated")
+		}
+
+		b, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("marshal delivery policy")
+			return
+		}
+		fmt.Printf("%s: %s\n", topic.Name, b)
+	},
+}
+
+func init() {
+	topicsCmd.AddCommand(deliveryCmd)
+
+	deliveryCmd.Flags().IntVar(&dripPerHour, "drip-per-hour", 0, "Max number of posts per hour, 0 - unlimited.")
+	deliveryCmd.Flags().StringVar(&dripWindow, "drip-window", "", "Daily time window to publish in, e.g. 09:00-21:00. Empty - all day.")
+	deliveryCmd.Flags().BoolVar(&dripSilent, "drip-silent", false, "Send messages without notification.")
+	deliveryCmd.Flags().BoolVar(&dripOff, "drip-off", false, "Remove topic drip settings to use global ones.")
+	deliveryCmd.Flags().StringVar(&quietHours, "quiet-hours", "", "Daily quiet hours, e.g. 22:00-08:00.")
+	deliveryCmd.Flags().StringVar(&quietMode, "quiet-mode", domain.QuietModeSilent, "What to do during quiet hours: silent or defer.")
+	deliveryCmd.Flags().BoolVar(&quietOff, "quiet-off", false, "Remove topic quiet hours to use global ones.")
+	deliveryCmd.Flags().StringVar(&timezone, "timezone", "", "Time zone of time windows, e.g. Europe/Moscow.")
+	deliveryCmd.Flags().BoolVar(&resetPolicy, "reset", false, "Remove all topic settings to use global policy.")
+}
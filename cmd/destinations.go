@@ -0,0 +1,187 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/caption"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+var (
+	dstTopic      string
+	dstName       string
+	dstChannel    int64
+	dstAccessHash int64
+	dstThread     int
+	dstCaption    string
+)
+
+// destinationsCmd represents the topics destinations command
+var destinationsCmd = &cobra.Command{
+	Use:   "destinations",
+	Short: "Manage additional destinations of topics",
+	Long: `Manage additional destinations of topics: broadcast channels, groups and topics of other forum groups.
+Media published to topic is sent to each destination of topic with the same document,
+file is uploaded once. Bot must be admin of destination chat.
+
+Examples:
+  tg topics destinations add --topic "Бхагавад-гита" --name channel --channel -1002586736001
+  tg topics destinations add --topic 3 --name mirror --channel 2586736002 --access-hash 456 --thread 12 --caption "{{.Title}}"
+  tg topics destinations list
+  tg topics destinations remove 1`,
+}
+
+// destinationsListCmd represents the topics destinations list command
+var destinationsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List destinations of topics",
+	Long:  `List destinations of all topics or of one topic with --topic.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			fmt.Printf("connect to database: %s\n", err)
+			return
+		}
+		defer d.Close()
+
+		var topicID uint64
+		if dstTopic != "" {
+			topic, err := resolveTopic(ctx, d, dstTopic)
+			if err != nil {
+				fmt.Printf("resolve topic: %s\n", err)
+				return
+			}
+			topicID = topic.ID
+		}
+
+		dsts, err := d.ListDestinations(ctx, topicID)
+		if err != nil {
+			fmt.Printf("load destinations: %s\n", err)
+			return
+		}
+
+		t := table.NewWriter()
+		t.SetStyle(table.StyleColoredDark)
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"ID", "Topic", "Name", "Channel ID", "Thread", "Caption Template"})
+		for _, dst := range dsts {
+			t.AppendRow(table.Row{
+				dst.ID, dst.Topic, dst.Name, dst.ChannelID, dst.MessageThreadID, dst.CaptionTemplate,
+			})
+		}
+		t.Render()
+	},
+}
+
+// destinationsAddCmd represents the topics destinations add command
+var destinationsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add destination to topic",
+	Long: `Add destination to topic. Channel ID can be given in bot API form with -100 prefix,
+see tg resolve. Without access hash channel is resolved by ID when media is sent.
+Caption template of destination overrides telegram.caption_template of config.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		if _, err := caption.Parse(dstCaption); err != nil {
+			log.Error().Err(err).Msg("invalid caption template")
+			return
+		}
+
+		channelID := domain.ChannelID(dstChannel)
+		if channelID == 0 {
+			log.Error().Int64("channel", dstChannel).Msg("destination must be channel or supergroup")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		topic, err := resolveTopic(ctx, d, dstTopic)
+		if err != nil {
+			log.Error().Err(err).Msg("resolve topic")
+			return
+		}
+
+		id, err := d.AddDestination(ctx, domain.Destination{
+			TopicID:         topic.ID,
+			Name:            dstName,
+			ChannelID:       channelID,
+			AccessHash:      dstAccessHash,
+			MessageThreadID: dstThread,
+			CaptionTemplate: dstCaption,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("add destination")
+			return
+		}
+		log.Info().Uint64("id", id).Str("topic", topic.Name).Str("name", dstName).Msg("destination added")
+	},
+}
+
+// destinationsRemoveCmd represents the topics destinations remove command
+var destinationsRemoveCmd = &cobra.Command{
+	Use:   "remove <destination id>",
+	Short: "Remove destination of topic",
+	Long:  `Remove destination of topic by ID, see tg topics destinations list.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			log.Error().Err(err).Str("destination", args[0]).Msg("parse destination id")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		if err := d.DeleteDestination(ctx, id); err != nil {
+			log.Error().Err(err).Msg("remove destination")
+			return
+		}
+		log.Info().Uint64("id", id).Msg("destination removed")
+	},
+}
+
+func init() {
+	topicsCmd.AddCommand(destinationsCmd)
+	destinationsCmd.AddCommand(destinationsListCmd, destinationsAddCmd, destinationsRemoveCmd)
+
+	destinationsListCmd.Flags().StringVar(&dstTopic, "topic", "", "Topic name or ID, destinations of all topics if empty.")
+
+	destinationsAddCmd.Flags().StringVar(&dstTopic, "topic", "", "Topic name or ID.")
+	destinationsAddCmd.Flags().StringVar(&dstName, "name", "", "Name of destination, it's shown in logs.")
+	destinationsAddCmd.Flags().Int64Var(&dstChannel, "channel", 0, "ID of channel or supergroup.")
+	destinationsAddCmd.Flags().Int64Var(&dstAccessHash, "access-hash", 0, "Access hash of channel for the bot, resolved by ID if 0.")
+	destinationsAddCmd.Flags().IntVar(&dstThread, "thread", 0, "Topic of forum group, 0 for channel or group without topics.")
+	destinationsAddCmd.Flags().StringVar(&dstCaption, "caption", "", "Caption template, telegram.caption_template of config if empty.")
+	for _, f := range []string{"topic", "name", "channel"} {
+		if err := destinationsAddCmd.MarkFlagRequired(f); err != nil {
+			log.Fatal().Err(err).Msgf("mark %s flag required", f)
+		}
+	}
+}
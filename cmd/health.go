@@ -0,0 +1,246 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxFloodWait is the longest FLOOD_WAIT that pipeline can be in and still be healthy.
+const maxFloodWait = 5 * time.Minute
+
+// state is runtime state of pipeline, it's reported by /status and /healthz.
+type state struct {
+	mu             sync.Mutex
+	connected      bool      // MTProto session is authorized and publishing queue
+	sessionErr     error     // error of the last session that failed to connect, e.g. auth or network failure
+	floodWaitUntil time.Time // end of the last FLOOD_WAIT
+	current        string    // title of media being published
+	lastUpload     time.Time
+}
+
+func (s *state) setConnected(connected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = connected
+	if connected {
+		s.sessionErr = nil
+	}
+}
+
+func (s *state) sessionClosed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		s.sessionErr = err
+	}
+	s.connected = false
+	s.current = ""
+}
+
+func (s *state) floodWait(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.floodWaitUntil = time.Now().Add(d)
+}
+
+func (s *state) publishing(title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = title
+}
+
+func (s *state) published(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = ""
+	s.lastUpload = at
+}
+
+// pipelineStatus is status of pipeline served by /status.
+type pipelineStatus struct {
+	Slug           string     `json:"slug"`
+	Queue          int        `json:"queue"`
+	Failed         int        `json:"failed"`
+	LastUpload     *time.Time `json:"last_upload,omitempty"`
+	Current        string     `json:"current,omitempty"`
+	Connected      bool       `json:"connected"`
+	Paused         bool       `json:"paused"`
+	FloodWaitUntil *time.Time `json:"flood_wait_until,omitempty"`
+	SessionError   string     `json:"session_error,omitempty"`
+}
+
+func (p *pipeline) status(ctx context.Context) (pipelineStatus, error) {
+	st := pipelineStatus{Slug: p.slug}
+
+	qs, err := d.GetQueueStats(ctx, p.configID)
+	if err != nil {
+		return st, err
+	}
+	st.Queue, st.Failed = qs.Queue, qs.Failed
+	if paused, err := d.IsPaused(ctx); err == nil {
+		st.Paused = paused
+	}
+	if c, err := d.GetConfig(ctx, p.slug); err == nil {
+		st.Paused = st.Paused || c.Paused
+	}
+
+	// state is copied, so processor isn't blocked by database query
+	p.state.mu.Lock()
+	st.Current = p.state.current
+	st.Connected = p.state.connected
+	if p.state.sessionErr != nil {
+		st.SessionError = p.state.sessionErr.Error()
+	}
+	floodWaitUntil, lastUpload := p.state.floodWaitUntil, p.state.lastUpload
+	p.state.mu.Unlock()
+
+	if time.Now().Before(floodWaitUntil) {
+		st.FloodWaitUntil = &floodWaitUntil
+	}
+	if !lastUpload.IsZero() {
+		st.LastUpload = &lastUpload
+	} else if t, err := d.GetRecentUploadTime(ctx, p.slug); err == nil {
+		st.LastUpload = &t
+	}
+	return st, nil
+}
+
+// Pause stops publishing of all topics of pipeline until Resume, media stays in queue.
+// Pause is saved in tg_config, it's the same as tg pause --slug. Pipeline without
+// tg_config publishes queue of all topics, so all bots are paused as with tg pause.
+func (p *pipeline) Pause(ctx context.Context) error {
+	if err := p.setPaused(ctx, true); err != nil {
+		return err
+	}
+	p.log.Info().Msg("publishing paused")
+	return nil
+}
+
+// Resume continues publishing paused by Pause or tg pause.
+func (p *pipeline) Resume(ctx context.Context) error {
+	if err := p.setPaused(ctx, false); err != nil {
+		return err
+	}
+	select {
+	case p.resumed <- struct{}{}:
+	default: // queue updater is already woken up
+	}
+	p.log.Info().Msg("publishing resumed")
+	return nil
+}
+
+func (p *pipeline) setPaused(ctx context.Context, paused bool) error {
+	if p.configID == 0 {
+		return d.SetPaused(ctx, paused)
+	}
+	return d.SetConfigPaused(ctx, p.slug, paused)
+}
+
+// StatusText returns status of pipeline for admin bot command /status.
+func (p *pipeline) StatusText(ctx context.Context) (string, error) {
+	st, err := p.status(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	session := "disconnected"
+	if st.Connected {
+		session = "connected"
+	}
+	fmt.Fprintf(&b, "%s: session %s", st.Slug, session)
+	if st.Paused {
+		b.WriteString(", paused")
+	}
+	fmt.Fprintf(&b, "\nqueue %d, failed %d", st.Queue, st.Failed)
+	if st.LastUpload != nil {
+		fmt.Fprintf(&b, "\nlast upload %s", st.LastUpload.Format(time.DateTime))
+	}
+	if st.Current != "" {
+		fmt.Fprintf(&b, "\npublishing '%s'", st.Current)
+	}
+	if st.FloodWaitUntil != nil {
+		fmt.Fprintf(&b, "\nflood wait until %s", st.FloodWaitUntil.Format(time.DateTime))
+	}
+	if st.SessionError != "" {
+		fmt.Fprintf(&b, "\nsession error: %s", st.SessionError)
+	}
+	return b.String(), nil
+}
+
+// problems returns reasons why pipeline is unhealthy.
+func (p *pipeline) problems() []string {
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+
+	var res []string
+	if !p.state.connected && p.state.sessionErr != nil {
+		res = append(res, fmt.Sprintf("%s: telegram session is not connected: %s", p.slug, p.state.sessionErr))
+	}
+	if wait := time.Until(p.state.floodWaitUntil); wait > maxFloodWait {
+		res = append(res, fmt.Sprintf("%s: flood wait for %s", p.slug, wait.Round(time.Second)))
+	}
+	return res
+}
+
+// healthHandler serves /healthz: database is available, telegram sessions are connected
+// and not stuck in flood wait.
+func healthHandler(pipelines []*pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var problems []string
+		if err := d.Ping(r.Context()); err != nil {
+			problems = append(problems, fmt.Sprintf("database: %s", err))
+		}
+		for _, p := range pipelines {
+			problems = append(problems, p.problems()...)
+		}
+
+		code, res := http.StatusOK, map[string]any{"status": "ok"}
+		if len(problems) > 0 {
+			code, res = http.StatusServiceUnavailable, map[string]any{"status": "unhealthy", "problems": problems}
+		}
+		writeJSON(w, code, res)
+	}
+}
+
+// statusHandler serves /status with queue sizes, last upload time and current item of every pipeline.
+func statusHandler(pipelines []*pipeline, started time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := struct {
+			Started   time.Time        `json:"started"`
+			Uptime    string           `json:"uptime"`
+			Pipelines []pipelineStatus `json:"pipelines"`
+		}{
+			Started: started,
+			Uptime:  time.Since(started).Round(time.Second).String(),
+		}
+
+		for _, p := range pipelines {
+			st, err := p.status(r.Context())
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				return
+			}
+			res.Pipelines = append(res.Pipelines, st)
+		}
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("write http response")
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gitlab.com/bvgm/tg/internal/logging"
+)
+
+// serveHTTP serves handler on addr until ctx is canceled.
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) {
+	log := logging.For(logging.ComponentHTTP)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("shutdown http server")
+		}
+	}()
+
+	log.Info().Str("addr", addr).Msg("serving http")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Str("addr", addr).Msg("http server stopped")
+	}
+}
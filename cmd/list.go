@@ -7,7 +7,6 @@ import (
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gitlab.com/bvgm/tg/internal/database"
 )
 
@@ -18,9 +17,8 @@ var listCmd = &cobra.Command{
 	Long:  `list configured topics`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
-		cfg := viper.GetViper()
 
-		d, err := database.New(cfg.GetString("database.dsn"))
+		d, err := database.New(cfg.Database.DSN)
 		if err != nil {
 			fmt.Printf("connect to database: %s\n", err)
 			return
@@ -36,10 +34,10 @@ var listCmd = &cobra.Command{
 		t := table.NewWriter()
 		t.SetStyle(table.StyleColoredDark)
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"ID", "Topic ID", "Topic Name", "Emoji ID", "CreatedAt", "TagID", "Tag Name"})
+		t.AppendHeader(table.Row{"ID", "Topic ID", "Topic Name", "Emoji ID", "CreatedAt", "TagID", "Tag Name", "Config", "Paused"})
 		for _, topic := range topics {
 			t.AppendRow(table.Row{
-				topic.ID, topic.MessageThreadID, topic.Name, topic.IconCustomEmojiID, topic.CreatedAt, topic.TagID, topic.Tag,
+				topic.ID, topic.MessageThreadID, topic.Name, topic.IconCustomEmojiID, topic.CreatedAt, topic.TagID, topic.Tag, topic.ConfigID, topic.Paused,
 			})
 		}
 		t.Render()
@@ -0,0 +1,87 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/mtproto"
+	"golang.org/x/term"
+)
+
+var loginPhone string
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Login to user account and save its session",
+	Long: `Login to Telegram user account by QR code or by code sent to phone and save session
+to telegram.session_file. Media is published by user account instead of bot with telegram.auth user.
+QR code is scanned in Telegram app: Settings > Devices > Link Desktop Device.
+Password of two-step verification is asked if it's enabled.
+
+Examples:
+  tg login
+  tg login --phone +79001234567`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		if cfg.Telegram.AppID == 0 || cfg.Telegram.AppHash == "" || cfg.Telegram.SessionFile == "" {
+			log.Error().Msg("app_id, app_hash and session_file required in telegram section of config file")
+			return
+		}
+
+		stdin := bufio.NewReader(os.Stdin)
+		user, err := mtproto.Login(ctx, mtproto.SesstionParams{
+			TgAppID:     cfg.Telegram.AppID,
+			TgAppHash:   cfg.Telegram.AppHash,
+			SessionFile: cfg.Telegram.SessionFile,
+			RateLimit:   cfg.Telegram.RateLimit,
+			Proxy:       cfg.Proxy,
+		}, mtproto.LoginParams{
+			Phone: loginPhone,
+			Code: func(context.Context) (string, error) {
+				fmt.Print("Code: ")
+				code, err := stdin.ReadString('\n')
+				return strings.TrimSpace(code), err
+			},
+			Password: func(context.Context) (string, error) {
+				fmt.Print("Password: ")
+				password, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if err != nil {
+					return "", fmt.Errorf("read password: %w", err)
+				}
+				return string(password), nil
+			},
+			ShowQR: func(_ context.Context, qrcode, url string) error {
+				fmt.Printf("Scan QR code in Telegram app or open %s\n%s", url, qrcode)
+				return nil
+			},
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("login")
+			return
+		}
+
+		log.Info().Int64("id", user.ID).Str("username", user.Username).Str("name", strings.TrimSpace(user.FirstName+" "+user.LastName)).
+			Str("file", cfg.Telegram.SessionFile).Msg("logged in, session saved")
+		if cfg.Telegram.Auth != domain.AuthUser {
+			log.Warn().Msg("set telegram.auth to user to publish media by user account")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().StringVar(&loginPhone, "phone", "", "Phone number in international format, login by QR code if empty.")
+}
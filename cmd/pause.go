@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/database"
+)
+
+var (
+	pauseSlug  string
+	pauseTopic string
+)
+
+// pauseCmd represents the pause command
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause publishing without stopping tg start",
+	Long: `Pause publishing of all bots, one bot with --slug or one topic with --topic.
+Pause of all bots covers topics without tg_config too.
+Pause is saved in database and checked by running tg start before each publish,
+media stays in queue until tg resume.
+
+Examples:
+  tg pause --slug goswami.ru
+  tg pause --topic "Бхагавад-гита"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setPaused(true)
+	},
+}
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume publishing paused by tg pause",
+	Long: `Resume publishing of all bots, one bot with --slug or one topic with --topic.
+Resume of all bots resumes bots paused with --slug too.
+Topic paused by tg pause --topic is resumed with the same flag only.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setPaused(false)
+	},
+}
+
+func setPaused(paused bool) {
+	ctx := context.Background()
+
+	d, err := database.New(cfg.Database.DSN)
+	if err != nil {
+		log.Error().Err(err).Msg("connect to database")
+		return
+	}
+	defer d.Close()
+
+	if pauseTopic != "" {
+		topic, err := resolveTopic(ctx, d, pauseTopic)
+		if err != nil {
+			log.Error().Err(err).Msg("resolve topic")
+			return
+		}
+		if err := d.SetTopicPaused(ctx, topic.ID, paused); err != nil {
+			log.Error().Err(err).Str("topic", topic.Name).Msg("set topic pause")
+			return
+		}
+		log.Info().Str("topic", topic.Name).Bool("paused", paused).Msg("topic pause changed")
+		return
+	}
+
+	if pauseSlug == "" {
+		if err := d.SetPaused(ctx, paused); err != nil {
+			log.Error().Err(err).Msg("set pause of all bots")
+			return
+		}
+		log.Info().Bool("paused", paused).Msg("pause of all bots changed")
+		return
+	}
+
+	if err := d.SetConfigPaused(ctx, pauseSlug, paused); err != nil {
+		log.Error().Err(err).Str("slug", pauseSlug).Msg("set bot pause")
+		return
+	}
+	log.Info().Str("slug", pauseSlug).Bool("paused", paused).Msg("bot pause changed")
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd, resumeCmd)
+
+	for _, c := range []*cobra.Command{pauseCmd, resumeCmd} {
+		c.Flags().StringVar(&pauseSlug, "slug", "", "Slug of tg_config, all bots if empty.")
+		c.Flags().StringVar(&pauseTopic, "topic", "", "Topic name or ID, other topics are not affected.")
+		c.MarkFlagsMutuallyExclusive("slug", "topic")
+	}
+}
@@ -0,0 +1,581 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/rs/zerolog"
+	"gitlab.com/bvgm/tg/internal/bot"
+	"gitlab.com/bvgm/tg/internal/caption"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/delivery"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/logging"
+	"gitlab.com/bvgm/tg/internal/metrics"
+	"gitlab.com/bvgm/tg/internal/mtproto"
+	"gitlab.com/bvgm/tg/internal/notify"
+	"gitlab.com/bvgm/tg/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("gitlab.com/bvgm/tg/cmd")
+
+// configLoader returns effective config of pipeline made from base config of file,
+// it's called on start and on reload.
+type configLoader func(ctx context.Context, base config.Config) (config.Config, error)
+
+// pipeline publishes queue of topics of one tg_config. Every pipeline has its own
+// settings, delivery scheduler and MTProto client with session, so several bots
+// can be served by one process.
+type pipeline struct {
+	slug      string
+	configID  int // tg_config.id, 0 - publish queue of all topics
+	load      configLoader
+	cfg       atomic.Pointer[config.Config]
+	scheduler *delivery.Scheduler
+	metrics   metrics.Pipeline
+	notifier  *notify.Notifier
+	state     state
+	resumed   chan struct{} // wakes up queue updater waiting for new data, see Resume
+	log       zerolog.Logger
+}
+
+func newPipeline(ctx context.Context, slug string, configID int, base config.Config, load configLoader, notifier *notify.Notifier) (*pipeline, error) {
+	c, err := load(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("load config of %s: %w", slug, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config of %s: %w", slug, err)
+	}
+
+	scheduler, err := delivery.New(c.Delivery)
+	if err != nil {
+		return nil, fmt.Errorf("create delivery scheduler of %s: %w", slug, err)
+	}
+
+	p := &pipeline{
+		slug:      slug,
+		configID:  configID,
+		load:      load,
+		scheduler: scheduler,
+		metrics:   metrics.For(slug),
+		notifier:  notifier,
+		resumed:   make(chan struct{}, 1),
+		log:       logging.For(logging.ComponentPipeline).With().Str("slug", slug).Logger(),
+	}
+	p.cfg.Store(&c)
+	return p, nil
+}
+
+// config returns current settings of pipeline, they can be changed by reload at any time.
+func (p *pipeline) config() config.Config {
+	return *p.cfg.Load()
+}
+
+// reload applies new settings which don't require new MTProto session, see config.Config.Reload.
+// Current settings are kept if new ones are invalid.
+func (p *pipeline) reload(ctx context.Context, base config.Config) error {
+	n, err := p.load(ctx, base)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := n.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	c, restart := p.config().Reload(n)
+	if err := p.scheduler.SetGlobal(c.Delivery); err != nil {
+		return err
+	}
+	p.cfg.Store(&c)
+
+	if len(restart) > 0 {
+		p.log.Warn().Strs("keys", restart).Msg("changed settings are applied after restart")
+	}
+	p.log.Info().
+		Str("performer", c.Server.Performer).
+		Dur("rate limit", c.Telegram.RateLimit).
+		Int("chunk size", c.Server.ChunkSize).
+		Dur("update interval", c.Server.UpdateInterval).
+		Msg("config reloaded")
+	return nil
+}
+
+// run fetches queue from database and publishes it until ctx is canceled.
+func (p *pipeline) run(ctx context.Context) {
+	// buffer size is not changed on reload, new chunk size limits fetching from database only
+	queue := make(chan domain.Audio, p.config().Server.ChunkSize)
+	wg := sync.WaitGroup{}
+
+	// queue updater
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.updateQueue(ctx, queue)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Second * 2) // to avoid duplication, this period must be less than updateInterval
+		defer ticker.Stop()
+
+		for {
+			select {
+			case err := <-p.processor(ctx, queue):
+				updateInterval := p.config().Server.UpdateInterval
+				if err != nil {
+					p.metrics.SessionRestarted()
+					p.notifier.SessionRestarted(p.slug, err)
+					p.log.Debug().Err(err).Dur("restart after", updateInterval).Msg("session closed, restarting processor")
+				} else {
+					p.log.Debug().Dur("restart after", updateInterval).Msg("processor finished, restarting")
+				}
+			case <-ticker.C:
+				p.log.Debug().Msg("let's check if there is something to do")
+				continue
+			case <-ctx.Done():
+				p.log.Info().Err(context.Cause(ctx)).Msg("queue processor stopped")
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(queue)
+}
+
+func (p *pipeline) updateQueue(ctx context.Context, queue chan<- domain.Audio) {
+	// queue items sent to processor or deferred by delivery policy. They are fetched
+	// again after updateInterval if they are still in database queue.
+	sent := make(map[uint64]time.Time)
+	for {
+		c := p.config()
+		updateInterval := c.Server.UpdateInterval
+
+		exclude := make([]uint64, 0, len(sent))
+		for id, at := range sent {
+			if time.Since(at) > updateInterval {
+				delete(sent, id)
+				continue
+			}
+			exclude = append(exclude, id)
+		}
+
+		data, err := p.fetchQueue(ctx, c.Server.ChunkSize, exclude)
+		if err != nil {
+			if err == database.ErrEmptyQueue {
+				p.log.Debug().Dur("wait", updateInterval).Msg("queue is empty, wait for new data")
+			} else {
+				p.log.Error().Dur("wait", updateInterval).Err(err).Msg("fetch queue from database failed.")
+			}
+			select {
+			case <-ctx.Done():
+				p.log.Info().Err(context.Cause(ctx)).Msg("queue updater stopped")
+				return
+			case <-time.After(updateInterval): // wait until new request for data
+				continue
+			case <-p.resumed:
+				continue
+			}
+		}
+		for _, a := range data {
+			// closed delivery window doesn't open session, media is fetched again
+			// after updateInterval
+			if decision := p.scheduler.Decide(a, time.Now()); decision.Defer {
+				sent[a.QueueID] = time.Now()
+				p.log.Debug().
+					Str("tag", a.Tag).
+					Str("title", a.Title).
+					Str("reason", decision.Reason).
+					Msg("media deferred by delivery policy")
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				p.log.Info().Err(context.Cause(ctx)).Msg("queue updater stopped")
+				return
+			case queue <- a:
+				sent[a.QueueID] = time.Now()
+				p.log.Debug().Str("audio", a.Title).Msg("added to queue")
+			}
+		}
+	}
+}
+
+// fetchQueue updates queue size metrics and returns head of queue.
+func (p *pipeline) fetchQueue(ctx context.Context, limit int, exclude []uint64) (data []domain.Audio, err error) {
+	ctx, span := tracer.Start(ctx, "fetch queue", trace.WithAttributes(
+		attribute.String("slug", p.slug),
+		attribute.Int("limit", limit),
+		attribute.Int("exclude", len(exclude)),
+	))
+	defer func() {
+		if err != nil && err != database.ErrEmptyQueue {
+			tracing.Fail(span, err)
+		}
+		span.SetAttributes(attribute.Int("fetched", len(data)))
+		span.End()
+	}()
+
+	if st, err := d.GetQueueStats(ctx, p.configID); err != nil {
+		p.log.Error().Err(err).Msg("get queue stats")
+	} else {
+		p.metrics.SetQueueSize(st.Queue, st.Failed)
+	}
+
+	return d.ListMediaQueue(ctx, p.configID, int32(limit), exclude)
+}
+
+func (p *pipeline) processor(ctx context.Context, queue chan domain.Audio) <-chan error {
+
+	c := p.config()
+
+	// session is kept open to answer commands and inline queries of listeners
+	if len(queue) == 0 && !c.Bot.Enabled() {
+		p.log.Debug().Msg("Nothing to do: queue is empty")
+		return nil
+	}
+
+	p.log.Info().Int("queue size", len(queue)).Msg("starting queue processor")
+
+	errc := make(chan error, 1)
+	defer close(errc)
+
+	params := mtproto.SesstionParams{
+		TgAppID:        c.Telegram.AppID,
+		TgAppHash:      c.Telegram.AppHash,
+		MtprotoGroupID: c.Telegram.MtprotoGroupID,
+		AccessHash:     c.Telegram.AccessHash,
+		TgBotToken:     c.Telegram.BotToken,
+		Threads:        c.Telegram.UploadThreads, // number of threads that will upload media to telegram
+		RateLimit:      c.Telegram.RateLimit,
+		Proxy:          c.Proxy,
+		OnFloodWait: func(wait time.Duration) {
+			p.metrics.FloodWait(wait)
+			p.state.floodWait(wait)
+			p.notifier.FloodWait(p.slug, wait)
+		},
+	}
+	if c.Telegram.Auth == domain.AuthUser {
+		params.SessionFile = c.Telegram.SessionFile
+	}
+	dispatcher := tg.NewUpdateDispatcher()
+	if c.Bot.Enabled() {
+		params.UpdateHandler = dispatcher
+	}
+
+	client, err := mtproto.New(ctx, params)
+	if err != nil {
+		p.log.Error().Err(err).Msg("create mtproto client")
+		errc <- err
+		return errc
+	}
+	defer client.Close()
+
+	var b *bot.Bot // delivers published media to subscribers of tags
+	if c.Bot.Enabled() {
+		b = bot.New(client.API(), &d, bot.Params{
+			Bot:            c.Bot,
+			ConfigID:       p.configID,
+			MtprotoGroupID: c.Telegram.MtprotoGroupID,
+			Group:          client,
+			Control:        p,
+		})
+		b.Register(dispatcher)
+	}
+
+	err = client.StartSession(ctx, func(pub mtproto.PublishAudioFunc) error {
+		p.state.setConnected(true)
+		if b != nil {
+			// subscribers are served while session is open
+			deliverCtx, stop := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				b.Run(deliverCtx)
+			}()
+			defer func() {
+				stop()
+				<-done
+			}()
+		}
+		for {
+			select {
+			case a := <-queue:
+				if err := p.publish(ctx, client, pub, b, a, len(queue)); err != nil {
+					return err
+				}
+
+			case <-time.NewTimer(time.Minute * 15).C:
+				if len(queue) == 0 && !c.Bot.Enabled() {
+					p.log.Info().Msg("Nothing to do: stop session")
+					return nil
+				}
+			case <-ctx.Done():
+				p.log.Info().Msg("processor canceled")
+				return nil
+			}
+		}
+	})
+	p.state.sessionClosed(err)
+
+	if err != nil {
+		p.log.Error().Err(err).Msg("queue processor: telegram bot session closed")
+		errc <- err
+	}
+	return errc
+}
+
+// publish sends media to telegram if delivery policy allows it. Error is returned
+// if session must be restarted, media that can't be published stays in queue or is
+// moved to failed queue. Published media is queued for subscribers of its tag if b is not nil.
+func (p *pipeline) publish(ctx context.Context, client *mtproto.MTProtoClient, pub mtproto.PublishAudioFunc, b *bot.Bot, a domain.Audio, queued int) (err error) {
+	ctx, span := tracer.Start(ctx, "publish media", trace.WithAttributes(
+		attribute.String("slug", p.slug),
+		attribute.Int64("queue.id", int64(a.QueueID)),
+		attribute.Int("media.id", a.MediaID),
+		attribute.String("media.tag", a.Tag),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	// settings can be reloaded while session is running
+	c := p.config()
+	client.SetRateLimit(c.Telegram.RateLimit)
+
+	// pause is checked before each publish, media stays in database queue and
+	// isn't fetched until resume
+	paused, err := d.IsTopicPaused(ctx, a.TopicID)
+	if err != nil {
+		p.log.Error().Err(err).Str("title", a.Title).Msg("check pause, media stays in queue")
+		return nil
+	}
+	if paused {
+		p.log.Debug().Str("tag", a.Tag).Str("title", a.Title).Msg("publishing is paused")
+		return nil
+	}
+
+	// drip limit may be reached by media sent to processor before, media stays
+	// in database queue and will be fetched again later
+	decision := p.scheduler.Decide(a, time.Now())
+	if decision.Defer {
+		p.log.Debug().
+			Str("tag", a.Tag).
+			Str("title", a.Title).
+			Str("reason", decision.Reason).
+			Msg("media deferred by delivery policy")
+		return nil
+	}
+
+	a = a.FullLocalPath(c.Storage.Audio).SetPerformer(c.Server.Performer).SetSilent(decision.Silent)
+
+	// media with several tags is posted once with all hashtags, topics of other tags
+	// get message of the first topic posted since media was queued, see telegram.multi_tag
+	var origin *domain.Message
+	if c.Telegram.MultiTag == domain.MultiTagForward || c.Telegram.MultiTag == domain.MultiTagDocument {
+		m, err := d.GetOriginMessage(ctx, a.MediaID, a.TopicID, a.Queued)
+		switch {
+		case err == nil:
+			origin = &m
+		case errors.Is(err, database.ErrMessageNotFound):
+			if a.Tags, err = d.ListMediaTags(ctx, a.MediaID, a.TopicID); err != nil {
+				p.log.Error().Err(err).Str("title", a.Title).Msg("media stays in queue")
+				return nil
+			}
+		default:
+			p.log.Error().Err(err).Str("title", a.Title).Msg("media stays in queue")
+			return nil
+		}
+	}
+
+	text, err := caption.Render(c.Telegram.CaptionTemplate, a)
+	if err != nil {
+		p.log.Error().Err(err).Str("title", a.Title).Msg("media stays in queue")
+		return nil
+	}
+	a = a.SetCaption(text)
+
+	p.log.Info().
+		Str("tag", a.Tag).
+		Str("title", a.Title).
+		Str("path", a.Path).
+		Int("queue size", queued).
+		Msg("sending media to telegram DC")
+
+	if err = d.DeleteFromQueue(ctx, p.configID, a.QueueID); err != nil {
+		if errors.Is(err, database.ErrNotInQueue) {
+			// already published or removed from queue by user
+			p.log.Debug().Str("title", a.Title).Msg("media is not in queue anymore")
+			return nil
+		}
+		return fmt.Errorf("remove '%s' from queue: %w", a.Title, err)
+	}
+
+	var sifToken *string
+	if origin == nil {
+		sifToken, err = d.GetSingleInstanceAudio(ctx, a.MediaID)
+		if err != nil {
+			if err != database.ErrNoSingleInstance {
+				return fmt.Errorf("get single instance audio: %w", err)
+			}
+		}
+	}
+
+	p.state.publishing(a.Title)
+	start := time.Now()
+	var res mtproto.Published
+	if origin != nil {
+		res, err = p.repost(ctx, client, c, a, *origin)
+	} else {
+		res, err = pub(ctx, a, sifToken)
+	}
+	if err != nil {
+		p.metrics.Failed(a.Tag)
+		p.log.Error().Err(err).Str("title", a.Title).Msg("move to failed queue")
+
+		if errdb := d.AddAudioToFailedQueue(ctx, a, err); errdb != nil {
+			return fmt.Errorf("add '%s' to failed queue: %w", a.Title, errdb)
+		}
+		p.notifier.Failed(p.slug, a.Title, err)
+
+		return fmt.Errorf("send media %s with tag: %s: %w", a.Path, a.Tag, err)
+	}
+
+	p.scheduler.Published(a, time.Now())
+	p.state.published(time.Now())
+	p.notifier.Published(p.slug)
+
+	// serialized file is the same if single instance file was reused
+	reused := origin != nil || (sifToken != nil && *sifToken == res.SingleInstance)
+	var size int64
+	if fi, err := os.Stat(a.Path); err == nil && !reused {
+		size = fi.Size()
+	}
+	p.metrics.SingleInstance(reused)
+	p.metrics.Published(a.Tag, size, time.Since(start))
+
+	// save telegram message ID to use it for single instance
+	if origin == nil {
+		err = d.LinkMediaToTelegram(ctx, a.MediaID, res.SingleInstance)
+		if err != nil {
+			return fmt.Errorf("add telegram message ID '%s' to media data: %w", a.Title, err)
+		}
+	}
+
+	// document is sent again in answers to listeners, see bot commands
+	msg := domain.Message{
+		MediaID:   a.MediaID,
+		Title:     a.Title,
+		TopicID:   a.TopicID,
+		Tag:       a.Tag,
+		MessageID: res.MessageID,
+		Document:  res.Document,
+	}
+	if origin != nil {
+		msg.OriginID = origin.ID
+	}
+	if msg.Document.ID != 0 {
+		if err := d.AddMessage(ctx, msg); err != nil {
+			return fmt.Errorf("save published message '%s': %w", a.Title, err)
+		}
+	}
+
+	err = d.SetRecentUploadTime(ctx, p.slug, time.Now())
+	if err != nil {
+		return fmt.Errorf("set recent upload time: %w", err)
+	}
+	p.log.Info().Str("tag", a.Tag).Str("title", a.Title).Str("file", filepath.Base(a.Path)).Msg("sent to telegram DC")
+
+	if msg.Document.ID != 0 {
+		p.mirror(ctx, client, a, msg.Document)
+	}
+
+	// subscribers get media in order of queue, publishing waits only when delivery queue is full
+	if b != nil && msg.Document.ID != 0 {
+		if err := b.Enqueue(ctx, a.TagID, msg); err != nil {
+			p.log.Error().Err(err).Str("title", a.Title).Msg("queue media for subscribers")
+		}
+	}
+	return nil
+}
+
+// mirror sends document published to topic to destinations of topic, e.g. broadcast channel or
+// topic of another group. Media is already published, so it isn't moved to failed queue when
+// destination fails, it would be published to topic again by retry. Failures are logged,
+// counted in metrics and alerted to admins instead, media is sent to destination manually.
+func (p *pipeline) mirror(ctx context.Context, client *mtproto.MTProtoClient, a domain.Audio, doc domain.Document) {
+	dsts, err := d.ListDestinations(ctx, a.TopicID)
+	if err != nil {
+		p.log.Error().Err(err).Str("title", a.Title).Msg("list destinations of topic")
+		return
+	}
+
+	for _, dst := range dsts {
+		// caption of topic is rendered with template of config
+		text := a.Caption
+		if dst.CaptionTemplate != "" {
+			if text, err = caption.Render(dst.CaptionTemplate, a); err != nil {
+				p.log.Error().Err(err).Str("destination", dst.Name).Str("title", a.Title).Msg("render caption of destination")
+				p.mirrorFailed(dst, a, err)
+				continue
+			}
+		}
+
+		if _, err := client.SendDocument(ctx, dst, doc, text, a.Silent); err != nil {
+			p.log.Error().Err(err).Str("destination", dst.Name).Str("title", a.Title).Msg("send media to destination")
+			p.mirrorFailed(dst, a, err)
+			continue
+		}
+		p.metrics.Mirrored(dst.Name, true)
+		p.log.Info().Str("destination", dst.Name).Str("title", a.Title).Msg("sent to destination")
+	}
+}
+
+func (p *pipeline) mirrorFailed(dst domain.Destination, a domain.Audio, err error) {
+	p.metrics.Mirrored(dst.Name, false)
+	p.notifier.MirrorFailed(p.slug, dst.Name, a.Title, err)
+}
+
+// repost forwards origin message to topic of a or sends its document again, depending on
+// telegram.multi_tag, so media with several tags isn't posted in each topic.
+func (p *pipeline) repost(ctx context.Context, client *mtproto.MTProtoClient, c config.Config, a domain.Audio, origin domain.Message) (res mtproto.Published, err error) {
+	topic := domain.Destination{
+		TopicID:         a.TopicID,
+		Name:            a.Tag,
+		ChannelID:       c.Telegram.MtprotoGroupID,
+		AccessHash:      c.Telegram.AccessHash,
+		MessageThreadID: a.MessageThreadID,
+	}
+
+	res.Document = origin.Document
+	if c.Telegram.MultiTag == domain.MultiTagForward {
+		res.MessageID, err = client.ForwardMessage(ctx, topic, origin.MessageID, a.Silent)
+	} else {
+		res.MessageID, err = client.SendDocument(ctx, topic, origin.Document, a.Caption, a.Silent)
+	}
+	if err != nil {
+		return res, err
+	}
+	p.log.Info().Str("mode", c.Telegram.MultiTag).Str("title", a.Title).Uint64("origin", origin.ID).Msg("media reposted to topic")
+	return res, nil
+}
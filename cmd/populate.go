@@ -6,19 +6,30 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
 )
 
 var (
 	since               time.Time
+	until               time.Time
 	recent              time.Duration
 	sinceSet, recentSet bool
 	tagID               int
+	tagName             string
+	topicName           string
+	populateSlug        string
+	mediaIDs            []int
+	skipPublished       bool
+	dryRun              bool
+	priority            int
 )
 
 // populateCmd represents the populate command
@@ -26,45 +37,125 @@ var populateCmd = &cobra.Command{
 	Use:   "populate",
 	Short: "Populate audio to database queue for uploading to telegram DC.",
 	Long: `Populate audio to database queue since specified date.
-Telegram bot processor will upload media from queue to telegram DC.`,
+Telegram bot processor will upload media from queue to telegram DC.
+
+Examples:
+  tg populate --since 2010-01-01 --until 2015-12-31 --tag "Бхагавад-гита" --skip-published
+  tg populate --media 12,34 --dry-run
+  tg populate --recent 168h --slug goswami.ru`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
-		config = viper.GetViper()
-
 		if cmd.Flags().Changed("since") && cmd.Flags().Changed("recent") {
 			log.Fatal().Msg("specify only one flag, --recent or --since")
 		}
 
-		if !cmd.Flags().Changed("since") && !cmd.Flags().Changed("recent") {
-			log.Fatal().Msg("specify flag, --recent or --since")
+		filtered := false
+		for _, f := range []string{"since", "recent", "until", "media", "tag", "tagid", "topic"} {
+			filtered = filtered || cmd.Flags().Changed(f)
+		}
+		if !filtered {
+			log.Fatal().Msg("specify flag, --recent, --since, --until, --media, --tag or --topic")
 		}
 
-		d, err := database.New(config.GetString("database.dsn"))
+		d, err := database.New(cfg.Database.DSN)
 		if err != nil {
 			log.Fatal().Err(err).Msg("connect to database")
 		}
 		defer d.Close()
 
+		configID, err := resolveConfigID(ctx, d, populateSlug)
+		if err != nil {
+			log.Fatal().Err(err).Str("slug", populateSlug).Msg("load bot config")
+		}
+
+		f := database.PopulateFilter{
+			ConfigID:      configID,
+			TagID:         tagID,
+			MediaIDs:      mediaIDs,
+			SkipPublished: skipPublished,
+		}
+
 		if cmd.Flags().Changed("since") {
-			fmt.Printf("populate audio since: %s\n", since)
-			if err := d.PopulateMedia(ctx, since, tagID); err != nil {
-				log.Fatal().Err(err).Msg("populate audio to queue")
+			f.Since = since
+		}
+		if cmd.Flags().Changed("recent") {
+			f.Since = time.Now().Add(-recent)
+		}
+		if cmd.Flags().Changed("until") {
+			f.Until = until
+		}
+
+		if tagName != "" {
+			if f.TagID, err = database.ResolveTag(ctx, &d, tagName); err != nil {
+				log.Fatal().Err(err).Msg("resolve tag")
 			}
 		}
 
-		if cmd.Flags().Changed("recent") {
-			fmt.Printf("populate recent audio: %s\n", recent)
-			if err := d.PopulateMedia(ctx, time.Now().Add(-recent), tagID); err != nil {
-				log.Fatal().Err(err).Msg("populate audio to queue")
+		if topicName != "" {
+			topic, err := resolveTopic(ctx, d, topicName)
+			if err != nil {
+				log.Fatal().Err(err).Msg("resolve topic")
 			}
+			f.TopicID = int(topic.ID)
 		}
+
+		if dryRun {
+			items, err := d.ListPopulateCandidates(ctx, f)
+			if err != nil {
+				log.Fatal().Err(err).Msg("list audio to populate")
+			}
+
+			t := table.NewWriter()
+			t.SetStyle(table.StyleColoredDark)
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"Media ID", "Title", "Occurrence Date", "Tag", "Topic", "Published"})
+			for _, item := range items {
+				t.AppendRow(table.Row{
+					item.MediaID, item.Title, item.OccurrenceDate.Format(time.DateOnly), item.Tag, item.Topic, item.Published,
+				})
+			}
+			t.AppendFooter(table.Row{"", "Would be queued", len(items)})
+			t.Render()
+			return
+		}
+
+		n, err := d.PopulateMedia(ctx, f, priority)
+		if err != nil {
+			log.Fatal().Err(err).Msg("populate audio to queue")
+		}
+		fmt.Printf("populated audio: %d\n", n)
 	},
 }
 
+// resolveTopic finds topic by its ID or name.
+func resolveTopic(ctx context.Context, d database.Tgdb, topic string) (domain.Topic, error) {
+	topics, err := d.ListAllTopics(ctx)
+	if err != nil {
+		return domain.Topic{}, err
+	}
+
+	id, _ := strconv.ParseUint(topic, 10, 64)
+	for _, t := range topics {
+		if t.ID == id || t.Name == topic {
+			return t, nil
+		}
+	}
+	return domain.Topic{}, fmt.Errorf("topic %q not found", topic)
+}
+
 func init() {
 	rootCmd.AddCommand(populateCmd)
 	populateCmd.Flags().TimeVarP(&since, "since", "s", time.Now(), []string{time.DateOnly, time.RFC3339}, "Time since populate audio to queue.")
+	populateCmd.Flags().TimeVarP(&until, "until", "u", time.Now(), []string{time.DateOnly, time.RFC3339}, "Time until populate audio to queue, inclusive.")
 	populateCmd.Flags().DurationVarP(&recent, "recent", "r", 0, "Specify duration populate audio to queue.")
 	populateCmd.Flags().IntVarP(&tagID, "tagid", "t", 0, "Tag ID to populate audio to queue.")
+	populateCmd.Flags().StringVar(&tagName, "tag", "", "Tag name (or ID) to populate audio to queue.")
+	populateCmd.Flags().StringVar(&topicName, "topic", "", "Topic name (or ID) to populate audio to queue.")
+	populateCmd.Flags().StringVar(&populateSlug, "slug", "", "Slug of tg_config, audio is added to topics of this bot only.")
+	populateCmd.Flags().IntSliceVarP(&mediaIDs, "media", "m", nil, "Media IDs to populate to queue, e.g. 12,34.")
+	populateCmd.Flags().BoolVar(&skipPublished, "skip-published", false, "Exclude media that already have telegram link.")
+	populateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print audio that would be queued without changing queue.")
+	populateCmd.Flags().IntVarP(&priority, "priority", "p", domain.PriorityBackfill, "Priority of populated audio in queue, higher is published first.")
+	populateCmd.MarkFlagsMutuallyExclusive("tag", "tagid")
 
 }
@@ -0,0 +1,312 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+var (
+	queueLimit    int32
+	queueMediaID  int
+	queueTag      string
+	queueAll      bool
+	queuePriority int
+	queueSlug     string
+)
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Inspect and manipulate the publish queue",
+	Long:  `Inspect and manipulate the publish queue: list, add, remove, clear, prioritize and retry failed media.`,
+}
+
+// queueListCmd represents the queue list command
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List media in queue in order of publishing",
+	Long:  `List media in queue in order of publishing. Use --slug to list queue of one bot only.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Fatal().Err(err).Msg("connect to database")
+		}
+		defer d.Close()
+
+		configID, err := resolveConfigID(ctx, d, queueSlug)
+		if err != nil {
+			log.Fatal().Err(err).Str("slug", queueSlug).Msg("load bot config")
+		}
+
+		items, err := d.ListQueue(ctx, configID, queueLimit)
+		if err != nil {
+			log.Fatal().Err(err).Msg("load queue")
+		}
+
+		audioBasePath := cfg.Storage.Audio
+
+		t := table.NewWriter()
+		t.SetStyle(table.StyleColoredDark)
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"ID", "Priority", "Media ID", "Title", "Tag", "Topic", "Occurrence Date", "File"})
+		for _, item := range items {
+			file := "yes"
+			if ok, err := item.FullLocalPath(audioBasePath).Exist(); err != nil {
+				file = err.Error()
+			} else if !ok {
+				file = "no"
+			}
+			t.AppendRow(table.Row{
+				item.QueueID, item.Priority, item.MediaID, item.Title, item.Tag, item.Topic, item.OccurrenceDate.Format(time.DateOnly), file,
+			})
+		}
+		t.AppendFooter(table.Row{"", "", "", "Total", len(items)})
+		t.Render()
+	},
+}
+
+// queueAddCmd represents the queue add command
+var queueAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add media to queue",
+	Long: `Add media to queue of every topic linked to media tags.
+Use --tag to add media to topic of single tag only.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		tagID, err := database.ResolveTag(ctx, &d, queueTag)
+		if err != nil {
+			log.Error().Err(err).Str("tag", queueTag).Msg("resolve tag")
+			return
+		}
+
+		n, err := d.AddMediaToQueue(ctx, queueMediaID, tagID, queuePriority)
+		if err != nil {
+			log.Error().Err(err).Int("media", queueMediaID).Msg("add media to queue")
+			return
+		}
+		log.Info().Int("media", queueMediaID).Int64("count", n).Msg("media added to queue")
+	},
+}
+
+// queueRemoveCmd represents the queue remove command
+var queueRemoveCmd = &cobra.Command{
+	Use:   "remove <queue id>...",
+	Short: "Remove items from queue",
+	Long: `Remove items from queue by queue ID. See "tg queue list" for IDs.
+Use --slug to remove items of one bot only.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		ids, err := parseQueueIDs(args)
+		if err != nil {
+			log.Error().Err(err).Msg("parse queue id")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		configID, err := resolveConfigID(ctx, d, queueSlug)
+		if err != nil {
+			log.Error().Err(err).Str("slug", queueSlug).Msg("load bot config")
+			return
+		}
+
+		for _, id := range ids {
+			if err := d.DeleteFromQueue(ctx, configID, id); err != nil {
+				log.Error().Err(err).Uint64("id", id).Msg("remove from queue")
+				continue
+			}
+			log.Info().Uint64("id", id).Msg("removed from queue")
+		}
+	},
+}
+
+// queueClearCmd represents the queue clear command
+var queueClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all media with tag from queue",
+	Long: `Remove all media with tag from queue. Use --all to clear whole queue.
+Use --slug to clear queue of one bot only.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		if queueTag == "" && !queueAll {
+			log.Error().Msg("specify flag, --tag or --all")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		configID, err := resolveConfigID(ctx, d, queueSlug)
+		if err != nil {
+			log.Error().Err(err).Str("slug", queueSlug).Msg("load bot config")
+			return
+		}
+
+		tagID, err := database.ResolveTag(ctx, &d, queueTag)
+		if err != nil {
+			log.Error().Err(err).Str("tag", queueTag).Msg("resolve tag")
+			return
+		}
+
+		n, err := d.ClearQueue(ctx, configID, tagID)
+		if err != nil {
+			log.Error().Err(err).Msg("clear queue")
+			return
+		}
+		log.Info().Str("tag", queueTag).Int64("count", n).Msg("queue cleared")
+	},
+}
+
+// queuePrioritizeCmd represents the queue prioritize command
+var queuePrioritizeCmd = &cobra.Command{
+	Use:   "prioritize <queue id>",
+	Short: "Move item to the head of queue",
+	Long: `Move item to the head of queue, it will be published first.
+Use --slug to move items of one bot only.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		ids, err := parseQueueIDs(args)
+		if err != nil {
+			log.Error().Err(err).Msg("parse queue id")
+			return
+		}
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		configID, err := resolveConfigID(ctx, d, queueSlug)
+		if err != nil {
+			log.Error().Err(err).Str("slug", queueSlug).Msg("load bot config")
+			return
+		}
+
+		if err := d.PrioritizeQueue(ctx, configID, ids[0]); err != nil {
+			log.Error().Err(err).Uint64("id", ids[0]).Msg("prioritize queue item")
+			return
+		}
+		log.Info().Uint64("id", ids[0]).Msg("moved to the head of queue")
+	},
+}
+
+// queueRetryFailedCmd represents the queue retry-failed command
+var queueRetryFailedCmd = &cobra.Command{
+	Use:   "retry-failed",
+	Short: "Move media from failed queue back to queue",
+	Long:  `Move media from failed queue back to queue. Use --slug to retry media of one bot only.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			log.Error().Err(err).Msg("connect to database")
+			return
+		}
+		defer d.Close()
+
+		configID, err := resolveConfigID(ctx, d, queueSlug)
+		if err != nil {
+			log.Error().Err(err).Str("slug", queueSlug).Msg("load bot config")
+			return
+		}
+
+		n, err := d.RetryFailed(ctx, configID, queuePriority)
+		if err != nil {
+			log.Error().Err(err).Msg("retry failed media")
+			return
+		}
+		log.Info().Int64("count", n).Msg("failed media moved to queue")
+	},
+}
+
+// resolveConfigID returns tg_config.id by its slug, empty slug gives 0 - all bots.
+func resolveConfigID(ctx context.Context, d database.Tgdb, slug string) (int, error) {
+	if slug == "" {
+		return 0, nil
+	}
+	c, err := d.GetConfig(ctx, slug)
+	if err != nil {
+		return 0, err
+	}
+	return c.ID, nil
+}
+
+func parseQueueIDs(args []string) ([]uint64, error) {
+	ids := make([]uint64, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.ParseUint(a, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("queue id %q: %w", a, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueListCmd, queueAddCmd, queueRemoveCmd, queueClearCmd, queuePrioritizeCmd, queueRetryFailedCmd)
+
+	queueListCmd.Flags().Int32Var(&queueLimit, "limit", 100, "Max number of items to show.")
+	queueListCmd.Flags().StringVar(&queueSlug, "slug", "", "Slug of tg_config, queue of all bots is shown if empty.")
+
+	queueAddCmd.Flags().IntVarP(&queueMediaID, "media", "m", 0, "Media ID to add to queue.")
+	if err := queueAddCmd.MarkFlagRequired("media"); err != nil {
+		log.Fatal().Err(err).Msg("mark media flag required")
+	}
+	queueAddCmd.Flags().StringVarP(&queueTag, "tag", "t", "", "Tag name or ID, add media to topic of this tag only.")
+	queueAddCmd.Flags().IntVarP(&queuePriority, "priority", "p", domain.PriorityFresh, "Priority of media in queue, higher is published first.")
+
+	queueClearCmd.Flags().StringVarP(&queueTag, "tag", "t", "", "Tag name or ID to remove from queue.")
+	queueClearCmd.Flags().BoolVar(&queueAll, "all", false, "Clear whole queue.")
+	queueClearCmd.Flags().StringVar(&queueSlug, "slug", "", "Slug of tg_config, queue of all bots is cleared if empty.")
+
+	queueRemoveCmd.Flags().StringVar(&queueSlug, "slug", "", "Slug of tg_config, items of all bots can be removed if empty.")
+	queuePrioritizeCmd.Flags().StringVar(&queueSlug, "slug", "", "Slug of tg_config, items of all bots can be moved if empty.")
+
+	queueRetryFailedCmd.Flags().StringVar(&queueSlug, "slug", "", "Slug of tg_config, failed media of all bots are retried if empty.")
+	queueRetryFailedCmd.Flags().IntVarP(&queuePriority, "priority", "p", domain.PriorityFresh, "Priority of media in queue, higher is published first.")
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/mtproto"
+)
+
+var (
+	resolveSlug        string
+	resolveWriteConfig bool
+	resolveWriteDB     bool
+)
+
+// resolveCmd represents the resolve command
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <@username|t.me link|-100id>",
+	Short: "Resolve group or channel and print its IDs and access hash",
+	Long: `Resolve group or channel with session of telegram.auth and print chat ID, MTProto ID and access hash
+for telegram.group_id, telegram.mtproto_group_id and telegram.access_hash settings.
+Bot must be member of private group or channel to resolve it by ID.
+
+Examples:
+  tg resolve @goswami_ru
+  tg resolve https://t.me/c/2586736000/12 --write-config
+  tg resolve -1002586736000 --slug goswami.ru --write-db`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		if _, _, err := mtproto.ParsePeer(args[0]); err != nil {
+			log.Error().Err(err).Msg("parse peer")
+			return
+		}
+		if resolveWriteDB && resolveSlug == "" {
+			log.Error().Msg("--write-db requires --slug")
+			return
+		}
+
+		var d database.Tgdb
+		if resolveSlug != "" {
+			var err error
+			if d, err = database.New(cfg.Database.DSN); err != nil {
+				log.Error().Err(err).Msg("connect to database")
+				return
+			}
+			defer d.Close()
+
+			if _, err := loadBotSettings(ctx, d, resolveSlug); err != nil {
+				log.Error().Err(err).Msg("load bot settings from database")
+				return
+			}
+		}
+
+		p, err := resolvePeer(ctx, args[0])
+		if err != nil {
+			log.Error().Err(err).Msg("resolve peer")
+			return
+		}
+
+		kind := "supergroup"
+		switch {
+		case p.Broadcast:
+			kind = "channel"
+		case p.Forum:
+			kind = "forum"
+		}
+		fmt.Printf("title: %s\ntype: %s\nusername: %s\n", p.Title, kind, p.Username)
+		fmt.Printf("group_id: %d\nmtproto_group_id: %d\naccess_hash: %d\n", p.ChatID(), p.ChannelID, p.AccessHash)
+
+		if resolveWriteConfig {
+			path := viper.ConfigFileUsed()
+			if path == "" {
+				log.Error().Msg("config file not found, use --config")
+				return
+			}
+			if err := config.WritePeer(path, p); err != nil {
+				log.Error().Err(err).Str("file", path).Msg("save peer to config file")
+				return
+			}
+			log.Info().Str("file", path).Msg("peer saved to config file")
+		}
+
+		if resolveWriteDB {
+			if err := d.SetConfigPeer(ctx, resolveSlug, p); err != nil {
+				log.Error().Err(err).Msg("save peer to tg_config")
+				return
+			}
+			log.Info().Str("slug", resolveSlug).Msg("peer saved to tg_config")
+		}
+	},
+}
+
+// resolvePeer starts bot or user session of telegram.auth to resolve target, see mtproto.ParsePeer.
+func resolvePeer(ctx context.Context, target string) (domain.Peer, error) {
+	var p domain.Peer
+	params := mtproto.SesstionParams{
+		TgAppID:        cfg.Telegram.AppID,
+		TgAppHash:      cfg.Telegram.AppHash,
+		TgBotToken:     cfg.Telegram.BotToken,
+		MtprotoGroupID: cfg.Telegram.MtprotoGroupID,
+		AccessHash:     cfg.Telegram.AccessHash,
+		RateLimit:      cfg.Telegram.RateLimit,
+		Proxy:          cfg.Proxy,
+	}
+	if cfg.Telegram.Auth == domain.AuthUser {
+		params.SessionFile = cfg.Telegram.SessionFile
+	}
+	if params.TgAppID == 0 || params.TgAppHash == "" || params.TgBotToken == "" && params.SessionFile == "" {
+		return p, errors.New("app_id, app_hash and bot_token or session_file required in config file")
+	}
+
+	client, err := mtproto.New(ctx, params)
+	if err != nil {
+		return p, err
+	}
+	defer client.Close()
+
+	err = client.StartSession(ctx, func(mtproto.PublishAudioFunc) error {
+		var err error
+		p, err = client.ResolvePeer(ctx, target)
+		return err
+	})
+	return p, err
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.Flags().StringVar(&resolveSlug, "slug", "", "Slug of tg_config to load bot settings from database.")
+	resolveCmd.Flags().BoolVar(&resolveWriteConfig, "write-config", false, "Save IDs and access hash to telegram section of config file.")
+	resolveCmd.Flags().BoolVar(&resolveWriteDB, "write-db", false, "Save IDs and access hash to settings of tg_config with --slug.")
+}
@@ -2,17 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/logging"
 )
 
 var (
 	cfgFile string
 	logFile string
+	cfg     config.Config // effective configuration, see initConfig
+
+	logCloser io.Closer // log file opened by initLogger, closed by Execute
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -31,6 +36,11 @@ Tag linked directly to telegram topic to publish a message in this topic.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	if logCloser != nil {
+		if err := logCloser.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Errorf("close log file: %w", err))
+		}
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -44,7 +54,7 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is $HOME/.tg.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&logFile, "log", "l", "tg.log", "log file (default is tg.log)")
+	rootCmd.PersistentFlags().StringVarP(&logFile, "log", "l", "tg.log", "log file (default is tg.log), rotated by size with log.max_size")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -66,7 +76,8 @@ func initConfig() {
 		viper.SetConfigName(".tg")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	config.SetDefaults(viper.GetViper())
+	config.InitEnv(viper.GetViper()) // read in environment variables that match, e.g. TG_DATABASE_DSN
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -74,31 +85,41 @@ func initConfig() {
 	} else {
 		fmt.Println(fmt.Errorf("read config: %w", err))
 	}
+
+	// secrets can be kept out of config file in environment or files
+	if err := config.LoadSecrets(viper.GetViper()); err != nil {
+		log.Fatal().Err(err).Msg("load secrets")
+	}
+
+	var err error
+	if cfg, err = config.Load(viper.GetViper()); err != nil {
+		log.Fatal().Err(err).Msg("load config")
+	}
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	initLogger()
 }
 
-func initLogger() {
-	fileLogger, err := os.OpenFile(
-		logFile,
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-		0664,
-	)
-	if err != nil {
-		log.Panic().Err(err).Str("file", logFile).Msg("open log file")
+// readConfig reads config file and secrets again and returns new config, cfg isn't changed.
+// Viper isn't safe for concurrent use, so it must be called from one goroutine only.
+func readConfig() (config.Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return config.Config{}, fmt.Errorf("read config: %w", err)
+	}
+	if err := config.LoadSecrets(viper.GetViper()); err != nil {
+		return config.Config{}, fmt.Errorf("load secrets: %w", err)
 	}
-	writers := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stderr}, fileLogger)
-
-	loglevel := zerolog.DebugLevel
-	switch viper.GetString("server.loglevel") {
-	case "debug":
-		loglevel = zerolog.DebugLevel
-	case "info":
-		loglevel = zerolog.InfoLevel
-	case "error":
-		loglevel = zerolog.ErrorLevel
+
+	c, err := config.Load(viper.GetViper())
+	if err != nil {
+		return config.Config{}, fmt.Errorf("load config: %w", err)
 	}
+	return c, nil
+}
 
-	log.Logger = zerolog.New(writers).Level(loglevel).With().Timestamp().Logger()
+func initLogger() {
+	var err error
+	if logCloser, err = logging.Setup(logFile, cfg.Server.LogLevel, cfg.Log); err != nil {
+		log.Panic().Err(err).Str("file", logFile).Msg("open log file")
+	}
 }
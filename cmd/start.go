@@ -5,7 +5,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -13,242 +15,302 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gitlab.com/bvgm/tg/internal/config"
 	"gitlab.com/bvgm/tg/internal/database"
 	"gitlab.com/bvgm/tg/internal/domain"
-	"gitlab.com/bvgm/tg/internal/mtproto"
+	"gitlab.com/bvgm/tg/internal/metrics"
+	"gitlab.com/bvgm/tg/internal/notify"
+	"gitlab.com/bvgm/tg/internal/proxy"
+	"gitlab.com/bvgm/tg/internal/tracing"
 )
 
 var (
-	chunkSize      int
-	updateInterval time.Duration
-	d              database.Tgdb
-	config         *viper.Viper
+	d           database.Tgdb
+	slug        string
+	startAll    bool
+	watchConfig bool
 )
 
 // startCmd represents the start command
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start service to upload media to telegram from queue.",
-	Long:  `Start service to upload media to telegram from queue.`,
+	Long: `Start service to upload media to telegram from queue.
+Bot settings are loaded from tg_config with --slug, they override config file values.
+Use --all to serve every bot of tg_config, each one publishes queue of its own topics.
+
+On SIGHUP (or config file change with --watch) performer, rate limit, chunk size,
+update interval, caption template and delivery policy are reloaded without dropping
+telegram session. Other settings are applied after restart.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
-		config = viper.GetViper()
-		log.Info().Interface("settings", config.AllSettings()).Msg("config settings")
-		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
-		defer close(sig)
+		started := time.Now()
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
 
-		chunkSize = config.GetInt("server.chunkSize")
-		if chunkSize <= 0 {
-			log.Fatal().Msg("chunk size must be greater than 0")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		// cfg isn't changed after start, reloaded config is passed to pipelines
+		base := cfg
+		configFile := viper.ConfigFileUsed()
+
+		shutdownTracing, err := tracing.Setup(ctx, base.Tracing)
+		if err != nil {
+			log.Fatal().Err(err).Msg("set up tracing")
 		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.Error().Err(err).Msg("flush traces")
+			}
+		}()
 
-		var err error
-		if d, err = database.New(config.GetString("database.dsn")); err != nil {
+		if d, err = database.New(base.Database.DSN); err != nil {
 			log.Fatal().Err(err).Msg("connect to database")
 		}
 		defer d.Close()
 
-		queue := make(chan domain.Audio, chunkSize)
-		defer close(queue)
-
-		updateInterval = config.GetDuration("server.update_interval") * time.Second
+		notifier := newNotifier(base)
+		defer notifier.Wait()
 
-		ctx, cancel := context.WithCancelCause(ctx)
-		defer cancel(nil)
-		wg := sync.WaitGroup{}
+		var pipelines []*pipeline
+		if startAll {
+			configs, err := d.ListConfigs(ctx)
+			if err != nil {
+				log.Fatal().Err(err).Msg("list bot configs")
+			}
+			if len(configs) == 0 {
+				log.Fatal().Msg("no bot configs in tg_config")
+			}
 
-		// queue updater
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			var (
-				cursor uint64
-				data   []domain.Audio
-				err    error
-			)
-			for {
+			for _, c := range configs {
+				// settings of tg_config override config file
+				load := func(ctx context.Context, base config.Config) (config.Config, error) {
+					bc, err := d.GetConfig(ctx, c.Slug)
+					if err != nil {
+						return config.Config{}, err
+					}
+					return base.WithBotSettings(bc.Settings), nil
+				}
 
-				data, cursor, err = d.ListMediaQueue(ctx, int32(chunkSize), cursor)
+				p, err := newPipeline(ctx, c.Slug, c.ID, base, load, notifier)
 				if err != nil {
-					if err == database.ErrEmptyQueue {
-						log.Debug().Dur("wait", updateInterval).Msg("queue is empty, wait for new data")
-					} else {
-						log.Error().Dur("wait", updateInterval).Err(err).Msg("fetch queue from database failed.")
-					}
-					select {
-					case <-ctx.Done():
-						log.Info().Err(context.Cause(ctx)).Msg("queue updater stopped")
-						return
-					case s := <-sig:
-						cancel(fmt.Errorf("got a signal %s", s.String()))
-						return
-					case <-time.After(updateInterval): // wait until new request for data
-						continue
-					}
+					log.Fatal().Err(err).Msg("create pipeline, see tg config validate --slug")
 				}
-				for _, a := range data {
-					select {
-					case <-ctx.Done():
-						log.Info().Err(context.Cause(ctx)).Msg("queue updater stopped")
-						return
-					case queue <- a:
-						log.Debug().Str("audio", a.Title).Msg("added to queue")
-					case s := <-sig:
-						cancel(fmt.Errorf("got a signal %s", s.String()))
-						return
-					}
+				pipelines = append(pipelines, p)
+			}
+		} else {
+			configID := 0
+			c, err := d.GetConfig(ctx, slug)
+			if errors.Is(err, database.ErrConfigNotFound) {
+				log.Warn().Err(err).Msg("bot settings are read from config file only")
+			} else if err != nil {
+				log.Fatal().Err(err).Msg("load bot settings from database")
+			} else {
+				configID = c.ID
+			}
+
+			// settings of tg_config override config file
+			load := func(ctx context.Context, base config.Config) (config.Config, error) {
+				bc, err := d.GetConfig(ctx, slug)
+				if errors.Is(err, database.ErrConfigNotFound) {
+					return base, nil
+				} else if err != nil {
+					return config.Config{}, err
 				}
+				return base.WithBotSettings(bc.Settings), nil
 			}
-		}()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+			p, err := newPipeline(ctx, slug, configID, base, load, notifier)
+			if err != nil {
+				log.Fatal().Err(err).Msg("create pipeline, see tg config validate")
+			}
+			log.Info().Interface("settings", config.Redact(viper.AllSettings())).Msg("config settings")
+			pipelines = append(pipelines, p)
+		}
 
-			ticker := time.NewTicker(time.Second * 2) // to avoid duplication, this period must be less than updateInterval
-			defer ticker.Stop()
+		changed := make(chan struct{}, 1)
+		if watchConfig {
+			if err := watchConfigFile(ctx, configFile, changed); err != nil {
+				log.Fatal().Err(err).Str("file", configFile).Msg("watch config file")
+			}
+		}
+
+		// settings are reloaded without dropping MTProto sessions,
+		// viper is used by this goroutine only after start
+		reload := func() {
+			c, err := readConfig()
+			if err != nil {
+				log.Error().Err(err).Msg("reload config, current settings are kept")
+				return
+			}
+			for _, p := range pipelines {
+				if err := p.reload(ctx, c); err != nil {
+					p.log.Error().Err(err).Msg("reload config, current settings are kept")
+				}
+			}
+		}
 
+		go func() {
 			for {
 				select {
-				case err := <-processor(ctx, queue):
-					if err != nil {
-						log.Debug().Err(err).Dur("restart after", updateInterval).Msg("session closed, restarting processor")
-					} else {
-						log.Debug().Dur("restart after", updateInterval).Msg("processor finished, restarting")
-					}
-				case <-ticker.C:
-					log.Debug().Msg("let's check if there is something to do")
-					continue
-				case <-ctx.Done():
-					log.Info().Err(context.Cause(ctx)).Msg("queue processor stopped")
-					return
 				case s := <-sig:
-					cancel(fmt.Errorf("got a signal: %s", s.String()))
+					cancel(fmt.Errorf("got a signal %s", s.String()))
+					return
+				case <-hup:
+					log.Info().Msg("got SIGHUP, reloading config")
+					reload()
+				case <-changed:
+					log.Info().Str("file", configFile).Msg("config file changed, reloading config")
+					reload()
+				case <-ctx.Done():
 					return
 				}
 			}
 		}()
 
+		wg := sync.WaitGroup{}
+		if base.Server.Listen != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			mux.Handle("/healthz", healthHandler(pipelines))
+			mux.Handle("/status", statusHandler(pipelines, started))
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serveHTTP(ctx, base.Server.Listen, mux)
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifier.RunSummary(ctx, func(ctx context.Context) (map[string]domain.QueueStats, error) {
+				res := make(map[string]domain.QueueStats, len(pipelines))
+				for _, p := range pipelines {
+					st, err := d.GetQueueStats(ctx, p.configID)
+					if err != nil {
+						return res, err
+					}
+					res[p.slug] = st
+				}
+				return res, nil
+			})
+		}()
+
+		for _, p := range pipelines {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.log.Info().Int("config", p.configID).Msg("starting pipeline")
+				p.run(ctx)
+			}()
+		}
 		wg.Wait()
 	},
 }
 
-func processor(ctx context.Context, queue chan domain.Audio) <-chan error {
-
-	if len(queue) == 0 {
-		log.Debug().Msg("Nothing to do: queue is empty")
-		return nil
+func init() {
+	rootCmd.AddCommand(startCmd)
+	startCmd.Flags().StringVar(&slug, "slug", domain.DefaultConfigSlug, "Slug of tg_config to load bot settings from, they override config file values.")
+	startCmd.Flags().BoolVar(&startAll, "all", false, "Serve all bots of tg_config, their settings override config file.")
+	startCmd.Flags().BoolVar(&watchConfig, "watch", false, "Reload config when config file is changed, like on SIGHUP.")
+	startCmd.Flags().String("listen", "", "Address of http server with /metrics, /healthz and /status, e.g. :9090.")
+	if err := viper.BindPFlag("server.listen", startCmd.Flags().Lookup("listen")); err != nil {
+		log.Fatal().Err(err).Msg("bind listen flag")
 	}
+	startCmd.Flags().Int("jobs", 2, "Number of upload goroutines to run (default 2).")
+	if err := viper.BindPFlag("server.jobs", startCmd.Flags().Lookup("jobs")); err != nil {
+		log.Fatal().Err(err).Msg("bind jobs flag")
+	}
+	startCmd.Flags().Int("chunkSize", 20, "Number of media to fetch from queue at once (default 20).")
+	if err := viper.BindPFlag("server.chunk_size", startCmd.Flags().Lookup("chunkSize")); err != nil {
+		log.Fatal().Err(err).Msg("bind chunkSize flag")
+	}
+	startCmd.Flags().DurationP("interval", "i", 15*time.Minute, "Interval between fetching media from queue.")
+	if err := viper.BindPFlag("server.update_interval", startCmd.Flags().Lookup("interval")); err != nil {
+		log.Fatal().Err(err).Msg("bind interval flag")
+	}
+}
 
-	log.Info().Int("queue size", len(queue)).Msg("starting queue processor")
-
-	errc := make(chan error, 1)
-	defer close(errc)
-
-	// jobs := viper.GetInt("server.jobs")
-	audioBasePath := config.GetString("storage.audio")
-
-	client, err := mtproto.New(ctx, mtproto.SesstionParams{
-		TgAppID:        config.GetInt("telegram.app_id"),
-		TgAppHash:      config.GetString("telegram.app_hash"),
-		MtprotoGroupID: config.GetInt64("telegram.mtproto_group_id"),
-		AccessHash:     config.GetInt64("telegram.access_hash"),
-		TgBotToken:     config.GetString("telegram.bot_token"),
-		Threads:        config.GetInt("telegram.upload_threads"), // number of threads that will upload media to telegram
-		RateLimit:      config.GetDuration("telegram.rate_limit"),
-	})
+// watchConfigFile signals changed when config file is written or replaced. Unlike viper.WatchConfig
+// file isn't read by watcher, so viper is used by reload goroutine only.
+func watchConfigFile(ctx context.Context, path string, changed chan<- struct{}) error {
+	if path == "" {
+		return errors.New("config file not found, use --config")
+	}
+	w, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Error().Err(err).Msg("create mtproto client")
-		errc <- err
-		return errc
+		return fmt.Errorf("create watcher: %w", err)
 	}
-	defer client.Close()
-
-	performer := "Бхакти Вигьяна Госвами"
-	if config.InConfig("server.performer") {
-		performer = config.GetString("server.performer")
+	// directory is watched, editors and kubernetes replace file instead of writing it
+	file := filepath.Clean(path)
+	if err := w.Add(filepath.Dir(file)); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("watch directory of config file: %w", err)
 	}
 
-	err = client.StartSession(ctx, func(pub mtproto.PublishAudioFunc) error {
+	go func() {
+		defer w.Close()
 		for {
 			select {
-			case a := <-queue:
-				log.Info().
-					Str("tag", a.Tag).
-					Str("title", a.Title).
-					Str("path", a.Path).
-					Int("queue size", len(queue)).
-					Msg("sending media to telegram DC")
-
-				if err = d.RemoveFromQueue(ctx, a.MediaID, a.TagID); err != nil {
-					return fmt.Errorf("remove '%s' from queue: %w", a.Title, err)
-				}
-
-				sifToken, err := d.GetSingleInstanceAudio(ctx, a.MediaID)
-				if err != nil {
-					if err != database.ErrNoSingleInstance {
-						return fmt.Errorf("get single instance audio: %w", err)
-					}
-				}
-
-				msgID, err := pub(a.FullLocalPath(audioBasePath).SetPerformer(performer), sifToken)
-				if err != nil {
-					log.Error().Err(err).Str("title", a.Title).Msg("move to failed queue")
-
-					if errdb := d.AddAudioToFailedQueue(ctx, a, err); errdb != nil {
-						return fmt.Errorf("add '%s' to failed queue: %w", a.Title, errdb)
-					}
-
-					return fmt.Errorf("send media %s with tag: %s: %w", a.Path, a.Tag, err)
+			case e, ok := <-w.Events:
+				if !ok {
+					return
 				}
-
-				// save telegram message ID to use it for single instance
-				err = d.LinkMediaToTelegram(ctx, a.MediaID, msgID)
-				if err != nil {
-					return fmt.Errorf("add telegram message ID '%s' to media data: %w", a.Title, err)
+				if filepath.Clean(e.Name) != file || e.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
 				}
-
-				err = d.SetRecentUploadTime(ctx, domain.DefaultConfigSlug, time.Now())
-				if err != nil {
-					return fmt.Errorf("set recent upload time: %w", err)
+				select {
+				case changed <- struct{}{}:
+				default:
 				}
-				log.Info().Str("tag", a.Tag).Str("title", a.Title).Str("file", filepath.Base(a.Path)).Msg("sent to telegram DC")
-
-			case <-time.NewTimer(time.Minute * 15).C:
-				if len(queue) == 0 {
-					log.Info().Msg("Nothing to do: stop session")
-					return nil
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
 				}
+				log.Warn().Err(err).Msg("watch config file")
 			case <-ctx.Done():
-				log.Info().Msg("processor canceled")
-				return nil
+				return
 			}
 		}
-	})
+	}()
+	return nil
+}
 
+// newNotifier returns notifier of admins, alerts are sent by bot of notify.bot_token
+// or telegram.bot_token. Alerts are dropped if admin chat and webhook are not set.
+func newNotifier(c config.Config) *notify.Notifier {
+	// alerts are sent through proxy.url like requests of bot API
+	client, err := proxy.HTTPClient(c.Proxy)
 	if err != nil {
-		log.Error().Err(err).Msg("queue processor: telegram bot session closed")
-		errc <- err
+		log.Error().Err(err).Msg("create http client of proxy, alerts are sent without proxy")
 	}
-	return errc
-}
 
-func init() {
-	rootCmd.AddCommand(startCmd)
-	startCmd.Flags().Int("jobs", 2, "Number of upload goroutines to run (default 2).")
-	if err := viper.BindPFlag("server.jobs", startCmd.Flags().Lookup("jobs")); err != nil {
-		log.Fatal().Err(err).Msg("bind jobs flag")
-	}
-	startCmd.Flags().Int("chunkSize", 20, "Number of media to fetch from queue at once (default 20).")
-	if err := viper.BindPFlag("server.chunkSize", startCmd.Flags().Lookup("chunkSize")); err != nil {
-		log.Fatal().Err(err).Msg("bind chunkSize flag")
+	var senders []notify.Sender
+	if c.Notify.AdminChatID != 0 {
+		token := c.Notify.BotToken
+		if token == "" {
+			token = c.Telegram.BotToken
+		}
+		if token == "" {
+			log.Warn().Msg("notify.admin_chat_id is set without bot token, set notify.bot_token")
+		} else {
+			senders = append(senders, notify.NewChat(token, c.Notify.AdminChatID, client))
+		}
 	}
-	startCmd.Flags().DurationVarP(&updateInterval, "interval", "i", 15*time.Minute, "Interval between fetching media from queue.")
-	if err := viper.BindPFlag("server.interval", startCmd.Flags().Lookup("interval")); err != nil {
-		log.Fatal().Err(err).Msg("bind interval flag")
+	if c.Notify.Webhook != "" {
+		senders = append(senders, notify.NewWebhook(c.Notify.Webhook, client))
 	}
+	return notify.New(c.Notify, senders...)
 }
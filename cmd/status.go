@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 <admin@goswami.ru>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+var statusSlug string
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show queue size, failed queue size, last upload time and pause of bots.",
+	Long: `Show queue size, failed queue size, last upload time and pause of every bot of tg_config.
+Use --slug to show one bot only. Runtime state of running service is served by
+tg start --listen on /status.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		d, err := database.New(cfg.Database.DSN)
+		if err != nil {
+			fmt.Printf("connect to database: %s\n", err)
+			os.Exit(1)
+		}
+		defer d.Close()
+
+		var configs []domain.Config
+		if statusSlug != "" {
+			c, err := d.GetConfig(ctx, statusSlug)
+			if err != nil {
+				fmt.Printf("load bot config: %s\n", err)
+				os.Exit(1)
+			}
+			configs = append(configs, *c)
+		} else if configs, err = d.ListConfigs(ctx); err != nil {
+			fmt.Printf("list bot configs: %s\n", err)
+			os.Exit(1)
+		}
+
+		t := table.NewWriter()
+		t.SetStyle(table.StyleColoredDark)
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Slug", "Queue", "Failed", "Last Upload", "Paused"})
+		if len(configs) == 0 {
+			// no tg_config, queue of all topics is published by tg start
+			configs = append(configs, domain.Config{Slug: "-"})
+		}
+		for _, c := range configs {
+			qs, err := d.GetQueueStats(ctx, c.ID)
+			if err != nil {
+				fmt.Printf("get queue stats of %s: %s\n", c.Slug, err)
+				os.Exit(1)
+			}
+			lastUpload := "-"
+			if !c.RecentUploadTime.IsZero() {
+				lastUpload = c.RecentUploadTime.Format(time.DateTime)
+			}
+			t.AppendRow(table.Row{c.Slug, qs.Queue, qs.Failed, lastUpload, c.Paused})
+		}
+		t.Render()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVar(&statusSlug, "slug", "", "Slug of tg_config, all bots are shown if empty.")
+}
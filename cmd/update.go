@@ -2,40 +2,61 @@ package cmd
 
 import (
 	"context"
+	"errors"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/proxy"
 	"gitlab.com/bvgm/tg/internal/tgapi"
 )
 
+var updateSlug string
+
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "update topics in telegram",
-	Long:  `Update topics by tag to sync with database. To publish tg_topic.created must be null`,
+	Long: `Update topics by tag to sync with database. To publish tg_topic.created must be null.
+Only topics of tg_config with --slug are published by bot of this config.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
-		cfg := viper.GetViper()
 
-		d, err := database.New(cfg.GetString("database.dsn"))
+		d, err := database.New(cfg.Database.DSN)
 		if err != nil {
 			log.Error().Err(err).Msg("connect to database")
 			return
 		}
 		defer d.Close()
 
+		configID := 0
+		c, err := loadBotSettings(ctx, d, updateSlug)
+		if errors.Is(err, database.ErrConfigNotFound) {
+			log.Warn().Err(err).Msg("bot settings are read from config file only")
+		} else if err != nil {
+			log.Error().Err(err).Msg("load bot settings from database")
+			return
+		} else {
+			configID = c.ID
+		}
+
 		topList, err := d.ListAllTopics(ctx)
 		if err != nil {
 			log.Error().Err(err).Msg("list all topics")
 			return
 		}
 
+		client, err := proxy.HTTPClient(cfg.Proxy)
+		if err != nil {
+			log.Error().Err(err).Msg("create http client of proxy")
+			return
+		}
+
 		cntUpdated := 0
-		tg := tgapi.New(topList)
+		tg := tgapi.New(cfg.Telegram.BotToken, cfg.Telegram.GroupID, topList, client)
 		for _, topic := range topList {
-			if topic.CreatedAt != nil {
+			if topic.CreatedAt != nil || (configID != 0 && topic.ConfigID != configID) {
 				continue
 			}
 
@@ -63,4 +84,5 @@ var updateCmd = &cobra.Command{
 
 func init() {
 	topicsCmd.AddCommand(updateCmd)
+	updateCmd.Flags().StringVar(&updateSlug, "slug", domain.DefaultConfigSlug, "Slug of tg_config to publish topics of.")
 }
@@ -19,7 +19,7 @@ import (
 	"github.com/gotd/td/tg"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"gitlab.com/bvgm/tg/internal/proxy"
 )
 
 var audioPath string
@@ -30,15 +30,14 @@ var uploadCmd = &cobra.Command{
 	Short: "Upload media to telegram",
 	Long:  `Upload audio to telegram without 50M limit. Limit 2G. app_id and app_hash should be specified in config file to work.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg := viper.GetViper()
-		tgAppID := cfg.GetInt("telegram.app_id")
-		tgAppHash := cfg.GetString("telegram.app_hash")
-		mtprotoGroupID := cfg.GetInt64("telegram.mtproto_group_id")
-		AccessHash := cfg.GetInt64("telegram.access_hash")
-		jobs := cfg.GetInt("server.jobs")
-		tgBotToken := cfg.GetString("telegram.bot_token")
-
-		if cfg.InConfig("telegram.app_id") == false || cfg.InConfig("telegram.app_hash") == false || cfg.InConfig("telegram.bot_token") == false {
+		tgAppID := cfg.Telegram.AppID
+		tgAppHash := cfg.Telegram.AppHash
+		mtprotoGroupID := cfg.Telegram.MtprotoGroupID
+		AccessHash := cfg.Telegram.AccessHash
+		jobs := cfg.Server.Jobs
+		tgBotToken := cfg.Telegram.BotToken
+
+		if tgAppID == 0 || tgAppHash == "" || tgBotToken == "" {
 			log.Error().Msg("app_id, app_hash and bot_token required in config file")
 			return
 		}
@@ -48,6 +47,12 @@ var uploadCmd = &cobra.Command{
 			return
 		}
 
+		resolver, err := proxy.Resolver(cfg.Proxy)
+		if err != nil {
+			log.Error().Err(err).Msg("create proxy resolver")
+			return
+		}
+
 		client := telegram.NewClient(
 			tgAppID,
 			tgAppHash,
@@ -58,11 +63,12 @@ var uploadCmd = &cobra.Command{
 				RetryInterval:  time.Second,
 				MaxRetries:     5,
 				SessionStorage: &SessionCache{},
+				Resolver:       resolver,
 			})
 
 		ctx, cancel := context.WithCancelCause(context.Background())
 
-		err := client.Run(ctx, func(ctx context.Context) error {
+		err = client.Run(ctx, func(ctx context.Context) error {
 			// Checking auth status.
 			status, err := client.Auth().Status(ctx)
 			if err != nil {
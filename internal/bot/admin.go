@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// adminQueueSize is number of queue items shown by /queue.
+const adminQueueSize = 10
+
+const adminHelp = `
+Admin commands:
+/status - state of session and queue
+/pause - pause publishing, media stays in queue
+/resume - resume publishing
+/retry_failed - move failed media back to queue
+/populate <tag> <since> - add media of tag since date, e.g. /populate Бхагавад-гита 2021-01-01
+/queue - head of queue`
+
+// Control is pipeline of bot operated by admins.
+type Control interface {
+	StatusText(ctx context.Context) (string, error)
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+}
+
+// AdminStore is storage of queue, see database.Tgdb. It's used by admin commands
+// with the same operations as CLI.
+type AdminStore interface {
+	RetryFailed(ctx context.Context, configID, priority int) (int64, error)
+	PopulateMedia(ctx context.Context, f database.PopulateFilter, priority int) (int64, error)
+	GetTagByName(ctx context.Context, name string) (int, error)
+	ListQueue(ctx context.Context, configID int, limit int32) ([]domain.QueueItem, error)
+}
+
+var adminCommands = map[string]bool{
+	"/status":       true,
+	"/pause":        true,
+	"/resume":       true,
+	"/retry_failed": true,
+	"/populate":     true,
+	"/queue":        true,
+}
+
+func (b *Bot) isAdmin(userID int64) bool {
+	return slices.Contains(b.params.Admins, userID)
+}
+
+// handleAdmin answers admin command, text of answer is returned.
+func (b *Bot) handleAdmin(ctx context.Context, userID int64, cmd string, args []string) (string, error) {
+	b.log.Info().Int64("user", userID).Str("command", cmd).Strs("args", args).Msg("admin command")
+
+	if b.params.Control == nil && (cmd == "/status" || cmd == "/pause" || cmd == "/resume") {
+		return "", errors.New("pipeline is not controlled by bot")
+	}
+
+	switch cmd {
+	case "/status":
+		return b.params.Control.StatusText(ctx)
+
+	case "/pause":
+		if err := b.params.Control.Pause(ctx); err != nil {
+			return "", err
+		}
+		return "Publishing is paused.", nil
+
+	case "/resume":
+		if err := b.params.Control.Resume(ctx); err != nil {
+			return "", err
+		}
+		return "Publishing is resumed.", nil
+
+	case "/retry_failed":
+		n, err := b.store.RetryFailed(ctx, b.params.ConfigID, domain.PriorityFresh)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Moved to queue: %d", n), nil
+
+	case "/populate":
+		f, err := b.populateFilter(ctx, args)
+		if err != nil {
+			return fmt.Sprintf("Can't populate: %s", err), nil
+		}
+		n, err := b.store.PopulateMedia(ctx, f, domain.PriorityBackfill)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Populated audio: %d", n), nil
+
+	case "/queue":
+		items, err := b.store.ListQueue(ctx, b.params.ConfigID, adminQueueSize)
+		if err != nil {
+			return "", err
+		}
+		return queueText(items), nil
+	}
+	return "", fmt.Errorf("unknown admin command %s", cmd)
+}
+
+// populateFilter parses "<tag> <since>" arguments of /populate, tag is name or ID.
+// Media are added to topics of bot's config only.
+func (b *Bot) populateFilter(ctx context.Context, args []string) (database.PopulateFilter, error) {
+	f := database.PopulateFilter{ConfigID: b.params.ConfigID}
+	if len(args) < 2 {
+		return f, errors.New("usage: /populate <tag> <since>, e.g. /populate Бхагавад-гита 2021-01-01")
+	}
+
+	since, err := time.Parse(time.DateOnly, args[len(args)-1])
+	if err != nil {
+		return f, fmt.Errorf("since must be date, e.g. 2021-01-01, got %q", args[len(args)-1])
+	}
+	f.Since = since
+
+	if f.TagID, err = database.ResolveTag(ctx, b.store, strings.Join(args[:len(args)-1], " ")); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// queueText returns queue items, one per line.
+func queueText(items []domain.QueueItem) string {
+	if len(items) == 0 {
+		return "Queue is empty."
+	}
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d. %s (%s, priority %d)", item.QueueID, item.Title, item.Tag, item.Priority)
+	}
+	return b.String()
+}
+
+// answerAdmin sends answer of admin command as plain text.
+func (b *Bot) answerAdmin(ctx context.Context, e tg.Entities, u *tg.UpdateNewMessage, userID int64, cmd string, args []string) error {
+	text, err := b.handleAdmin(ctx, userID, cmd, args)
+	if err != nil {
+		return err
+	}
+	_, err = b.sender.Answer(e, u).Text(ctx, text)
+	return err
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+type tagStore struct {
+	Store
+	tags map[string]int
+}
+
+func (s tagStore) GetTagByName(_ context.Context, name string) (int, error) {
+	id, ok := s.tags[name]
+	if !ok {
+		return 0, database.ErrTagNotFound
+	}
+	return id, nil
+}
+
+func TestPopulateFilter(t *testing.T) {
+	b := &Bot{store: tagStore{tags: map[string]int{"Шримад-Бхагаватам": 3, "108": 5}}, params: Params{ConfigID: 2}}
+	ctx := context.Background()
+
+	f, err := b.populateFilter(ctx, []string{"Шримад-Бхагаватам", "2021-01-02"})
+	require.NoError(t, err)
+	require.Equal(t, database.PopulateFilter{ConfigID: 2, TagID: 3, Since: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)}, f)
+
+	f, err = b.populateFilter(ctx, []string{"7", "2021-01-02"})
+	require.NoError(t, err)
+	require.Equal(t, 7, f.TagID)
+
+	f, err = b.populateFilter(ctx, []string{"108", "2021-01-02"})
+	require.NoError(t, err)
+	require.Equal(t, 5, f.TagID) // numeric name
+
+	_, err = b.populateFilter(ctx, []string{"Гита", "2021-01-02"})
+	require.ErrorIs(t, err, database.ErrTagNotFound)
+
+	_, err = b.populateFilter(ctx, []string{"Шримад-Бхагаватам", "yesterday"})
+	require.Error(t, err)
+
+	_, err = b.populateFilter(ctx, []string{"2021-01-02"})
+	require.Error(t, err)
+}
+
+func TestQueueText(t *testing.T) {
+	require.Equal(t, "Queue is empty.", queueText(nil))
+
+	items := []domain.QueueItem{
+		{Priority: 100, Audio: domain.Audio{QueueID: 1, Title: "Лекция 1", Tag: "Гита"}},
+		{Audio: domain.Audio{QueueID: 2, Title: "Лекция 2", Tag: "Гита"}},
+	}
+	require.Equal(t, "1. Лекция 1 (Гита, priority 100)\n2. Лекция 2 (Гита, priority 0)", queueText(items))
+}
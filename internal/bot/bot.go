@@ -0,0 +1,250 @@
+// Package bot answers commands of listeners in private chat with bot:
+// /latest [tag], /search <words>, /topics and subscriptions to tags, and inline
+// queries in any chat. Media are sent as documents of already published messages,
+// so files are not uploaded again.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/rs/zerolog"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/logging"
+	"golang.org/x/time/rate"
+)
+
+const help = `Commands:
+/latest [tag] - recently published media
+/search <words> - find published media by title
+/topics - list of topics
+/subscribe <tag> - receive new media of tag here
+/unsubscribe <tag> - stop receiving media of tag
+/mysubs - list of your subscriptions`
+
+// Store is storage of published messages, topics and queue, see database.Tgdb.
+type Store interface {
+	AdminStore
+	SubscriptionStore
+	ListLatestMessages(ctx context.Context, configID int, tag string, limit int) ([]domain.Message, error)
+	SearchMessages(ctx context.Context, configID int, words []string, limit, offset int) ([]domain.Message, error)
+	ListAllTopics(ctx context.Context) ([]domain.Topic, error)
+}
+
+// Group returns peer of group of topics, access hash is resolved if it's not set in config,
+// see mtproto.MTProtoClient.GroupPeer.
+type Group interface {
+	GroupPeer(ctx context.Context) (*tg.InputPeerChannel, error)
+}
+
+// Params are settings of bot.
+type Params struct {
+	config.Bot
+	ConfigID       int     // tg_config.id, messages and topics of other configs are not shown. 0 - all
+	MtprotoGroupID int64   // group of topics, chat ID without -100 prefix
+	Group          Group   // group of topics, messages are forwarded from it
+	Control        Control // pipeline of bot, required for /status, /pause and /resume
+}
+
+// Bot handles commands and inline queries of listeners and delivers media to subscribers.
+type Bot struct {
+	api     *tg.Client
+	store   Store
+	sender  *message.Sender
+	params  Params
+	limiter *rate.Limiter // of messages to subscribers
+	inline  *inlineCache  // pages of inline results
+	log     zerolog.Logger
+
+	deliveries chan delivery // published messages for subscribers, see Enqueue
+	recent     *recentMedia  // subscribers which recent media were sent to
+}
+
+func New(api *tg.Client, store Store, p Params) *Bot {
+	if p.Results <= 0 {
+		p.Results = config.DefaultResults
+	}
+	if p.SubscriptionRate <= 0 {
+		p.SubscriptionRate = config.DefaultSubscriptionRate
+	}
+	return &Bot{
+		api:     api,
+		store:   store,
+		sender:  message.NewSender(api),
+		params:  p,
+		limiter: rate.NewLimiter(rate.Limit(p.SubscriptionRate), 1),
+		inline:  newInlineCache(p.CacheTime),
+		log:     logging.For(logging.ComponentBot),
+
+		deliveries: make(chan delivery, deliveryQueueSize),
+		recent:     newRecentMedia(),
+	}
+}
+
+// Register adds handlers of enabled commands and inline queries to dispatcher of session updates.
+func (b *Bot) Register(d tg.UpdateDispatcher) {
+	if b.params.Commands || len(b.params.Admins) > 0 {
+		d.OnNewMessage(b.onNewMessage)
+	}
+	if b.params.Inline {
+		d.OnBotInlineQuery(b.onInlineQuery)
+	}
+}
+
+func (b *Bot) onNewMessage(ctx context.Context, e tg.Entities, u *tg.UpdateNewMessage) error {
+	msg, ok := u.Message.(*tg.Message)
+	if !ok || msg.Out {
+		return nil
+	}
+	// commands are answered in private chat only, group is for publishing
+	user, ok := msg.PeerID.(*tg.PeerUser)
+	if !ok {
+		return nil
+	}
+
+	cmd, args := parseCommand(msg.Message)
+	if cmd == "" {
+		return nil
+	}
+	admin := b.isAdmin(user.UserID)
+	if !admin && !b.params.Commands {
+		return nil // bot answers admins only
+	}
+	b.log.Debug().Str("command", cmd).Strs("args", args).Msg("got command")
+
+	var err error
+	if admin && adminCommands[cmd] {
+		err = b.answerAdmin(ctx, e, u, user.UserID, cmd, args)
+	} else {
+		err = b.handle(ctx, e, u, user.UserID, cmd, args, admin)
+	}
+	if err != nil {
+		b.log.Error().Err(err).Str("command", cmd).Msg("answer command")
+		if _, err := b.sender.Answer(e, u).Text(ctx, "Something went wrong, try again later."); err != nil {
+			return fmt.Errorf("answer error: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *Bot) handle(ctx context.Context, e tg.Entities, u *tg.UpdateNewMessage, userID int64, cmd string, args []string, admin bool) error {
+	switch cmd {
+	case "/latest":
+		tag := strings.TrimPrefix(strings.Join(args, " "), "#")
+		msgs, err := b.store.ListLatestMessages(ctx, b.params.ConfigID, tag, b.params.Results)
+		if err != nil {
+			return err
+		}
+		return b.sendMessages(ctx, e, u, msgs)
+
+	case "/search":
+		if len(args) == 0 {
+			_, err := b.sender.Answer(e, u).Text(ctx, "Usage: /search <words>")
+			return err
+		}
+		msgs, err := b.store.SearchMessages(ctx, b.params.ConfigID, args, b.params.Results, 0)
+		if err != nil {
+			return err
+		}
+		return b.sendMessages(ctx, e, u, msgs)
+
+	case "/topics":
+		topics, err := b.store.ListAllTopics(ctx)
+		if err != nil {
+			return err
+		}
+		var text []styling.StyledTextOption
+		for _, t := range topics {
+			if t.MessageThreadID == 0 || (b.params.ConfigID != 0 && t.ConfigID != b.params.ConfigID) {
+				continue // not created in group or topic of other bot
+			}
+			text = append(text, styling.TextURL(t.Name, topicLink(b.params.MtprotoGroupID, t.MessageThreadID)), styling.Plain("\n"))
+		}
+		if len(text) == 0 {
+			_, err := b.sender.Answer(e, u).Text(ctx, "No topics yet.")
+			return err
+		}
+		_, err = b.sender.Answer(e, u).StyledText(ctx, text...)
+		return err
+
+	case "/subscribe", "/unsubscribe":
+		if len(args) == 0 {
+			_, err := b.sender.Answer(e, u).Text(ctx, fmt.Sprintf("Usage: %s <tag>", cmd))
+			return err
+		}
+		text, err := b.subscribe(ctx, e, userID, cmd == "/subscribe", strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		_, err = b.sender.Answer(e, u).Text(ctx, text)
+		return err
+
+	case "/mysubs":
+		text, err := b.subscriptions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		_, err = b.sender.Answer(e, u).Text(ctx, text)
+		return err
+
+	default:
+		text := help
+		if admin {
+			text += "\n" + adminHelp
+		}
+		_, err := b.sender.Answer(e, u).Text(ctx, text)
+		return err
+	}
+}
+
+// sendMessages sends documents of published messages. Message is forwarded from
+// group if file reference of document is expired.
+func (b *Bot) sendMessages(ctx context.Context, e tg.Entities, u *tg.UpdateNewMessage, msgs []domain.Message) error {
+	if len(msgs) == 0 {
+		_, err := b.sender.Answer(e, u).Text(ctx, "Nothing found.")
+		return err
+	}
+
+	seen := make(map[int]bool) // media published in several topics is sent once
+	for _, m := range msgs {
+		if seen[m.MediaID] {
+			continue
+		}
+		seen[m.MediaID] = true
+
+		_, err := b.sender.Answer(e, u).Media(ctx, message.Document(m.Document, styling.Plain(m.Title)))
+		if tgerr.Is(err, "FILE_REFERENCE_EXPIRED") && m.MessageID != 0 && b.params.Group != nil {
+			group, gerr := b.params.Group.GroupPeer(ctx)
+			if gerr != nil {
+				return fmt.Errorf("resolve group to forward '%s': %w", m.Title, gerr)
+			}
+			_, err = b.sender.Answer(e, u).ForwardIDs(group, m.MessageID).Send(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("send '%s': %w", m.Title, err)
+		}
+	}
+	return nil
+}
+
+// parseCommand splits text of message to command and arguments,
+// e.g. "/search@bot gita  1" to "/search" and ["gita", "1"]. Command is empty if text is not a command.
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	cmd, _, _ := strings.Cut(fields[0], "@")
+	return strings.ToLower(cmd), fields[1:]
+}
+
+// topicLink returns link to topic of private group.
+func topicLink(groupID int64, messageThreadID int) string {
+	return fmt.Sprintf("https://t.me/c/%d/%d", groupID, messageThreadID)
+}
@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		cmd  string
+		args []string
+	}{
+		{"/latest", "/latest", []string{}},
+		{"/Latest@tg_bot  Бхагавад-гита ", "/latest", []string{"Бхагавад-гита"}},
+		{"/search gita 1", "/search", []string{"gita", "1"}},
+		{"hello /topics", "", nil},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		cmd, args := parseCommand(tt.text)
+		require.Equal(t, tt.cmd, cmd, tt.text)
+		require.Equal(t, tt.args, args, tt.text)
+	}
+}
+
+func TestTopicLink(t *testing.T) {
+	require.Equal(t, "https://t.me/c/1234567890/42", topicLink(1234567890, 42))
+}
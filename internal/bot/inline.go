@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// inlinePageSize is number of inline results in one answer, telegram allows up to 50.
+const inlinePageSize = 20
+
+const (
+	inlineCacheTTL  = 30 * time.Second // of pages in local cache if cache_time isn't set
+	inlineCacheSize = 512              // max number of pages in local cache
+)
+
+// onInlineQuery answers "@bot <words>" with published media which title or teaser
+// contains all words. Next pages are requested by client with offset of the answer.
+func (b *Bot) onInlineQuery(ctx context.Context, _ tg.Entities, u *tg.UpdateBotInlineQuery) error {
+	offset, err := strconv.Atoi(u.Offset)
+	if err != nil || offset < 0 {
+		offset = 0 // the first page
+	}
+	words := strings.Fields(u.Query)
+	b.log.Debug().Strs("words", words).Int("offset", offset).Msg("got inline query")
+
+	// results don't depend on user, so page of query is shared by all users
+	key := inlineKey(words, offset)
+	msgs, ok := b.inline.get(key)
+	if !ok {
+		msgs, err = b.store.SearchMessages(ctx, b.params.ConfigID, words, inlinePageSize, offset)
+		if err != nil {
+			b.log.Error().Err(err).Str("query", u.Query).Msg("search inline results")
+			return nil
+		}
+		b.inline.put(key, msgs)
+	}
+
+	req := &tg.MessagesSetInlineBotResultsRequest{
+		QueryID: u.QueryID,
+		Results: inlineResults(msgs),
+		// answers are cached by telegram for all users, query is not personal
+		CacheTime:  int(b.params.CacheTime.Seconds()),
+		NextOffset: nextOffset(offset, len(msgs)),
+	}
+	if _, err := b.api.MessagesSetInlineBotResults(ctx, req); err != nil {
+		b.log.Error().Err(err).Str("query", u.Query).Msg("answer inline query")
+	}
+	return nil
+}
+
+// inlineResults returns results which reference documents of published messages.
+func inlineResults(msgs []domain.Message) []tg.InputBotInlineResultClass {
+	res := make([]tg.InputBotInlineResultClass, 0, len(msgs))
+	for _, m := range msgs {
+		res = append(res, &tg.InputBotInlineResultDocument{
+			ID:          strconv.Itoa(m.MediaID),
+			Type:        "audio",
+			Title:       m.Title,
+			Description: m.Tag,
+			Document: &tg.InputDocument{
+				ID:            m.Document.ID,
+				AccessHash:    m.Document.AccessHash,
+				FileReference: m.Document.FileReference,
+			},
+			SendMessage: &tg.InputBotInlineMessageMediaAuto{Message: m.Title},
+		})
+	}
+	return res
+}
+
+// nextOffset returns offset of the next page, empty if page is the last one.
+func nextOffset(offset, n int) string {
+	if n < inlinePageSize {
+		return ""
+	}
+	return strconv.Itoa(offset + n)
+}
+
+// inlineKey returns key of page of inline results, case of words doesn't change results.
+func inlineKey(words []string, offset int) string {
+	return strings.ToLower(strings.Join(words, " ")) + "\x00" + strconv.Itoa(offset)
+}
+
+// inlineCache keeps pages of inline results for a short time. Telegram caches answers
+// by exact query, while typing produces many queries and next pages are requested
+// again by every user, so database is searched once per page.
+type inlineCache struct {
+	ttl   time.Duration
+	now   func() time.Time
+	mu    sync.Mutex
+	pages map[string]inlinePage
+}
+
+type inlinePage struct {
+	msgs    []domain.Message
+	expires time.Time
+}
+
+func newInlineCache(ttl time.Duration) *inlineCache {
+	if ttl <= 0 {
+		ttl = inlineCacheTTL
+	}
+	return &inlineCache{ttl: ttl, now: time.Now, pages: make(map[string]inlinePage)}
+}
+
+func (c *inlineCache) get(key string) ([]domain.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pages[key]
+	if !ok || !c.now().Before(p.expires) {
+		return nil, false
+	}
+	return p.msgs, true
+}
+
+func (c *inlineCache) put(key string, msgs []domain.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.pages) >= inlineCacheSize {
+		for k, p := range c.pages {
+			if !now.Before(p.expires) {
+				delete(c.pages, k)
+			}
+		}
+		if len(c.pages) >= inlineCacheSize {
+			clear(c.pages) // all pages are fresh, cache is filled again
+		}
+	}
+	c.pages[key] = inlinePage{msgs: msgs, expires: now.Add(c.ttl)}
+}
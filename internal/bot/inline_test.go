@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestInlineResults(t *testing.T) {
+	msgs := []domain.Message{{
+		MediaID:  7,
+		Title:    "Бхагавад-гита 2.13",
+		Tag:      "Бхагавад-гита",
+		Document: domain.Document{ID: 1, AccessHash: 2, FileReference: []byte{3}},
+	}}
+
+	res := inlineResults(msgs)
+	require.Len(t, res, 1)
+	doc, ok := res[0].(*tg.InputBotInlineResultDocument)
+	require.True(t, ok)
+	require.Equal(t, "7", doc.ID)
+	require.Equal(t, &tg.InputDocument{ID: 1, AccessHash: 2, FileReference: []byte{3}}, doc.Document)
+}
+
+func TestNextOffset(t *testing.T) {
+	require.Equal(t, "20", nextOffset(0, inlinePageSize))
+	require.Equal(t, "40", nextOffset(20, inlinePageSize))
+	require.Equal(t, "", nextOffset(20, 3))
+}
+
+func TestInlineCache(t *testing.T) {
+	now := time.Now()
+	c := newInlineCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	key := inlineKey([]string{"Бхагавад-Гита", "2.13"}, 20)
+	require.Equal(t, key, inlineKey([]string{"бхагавад-гита", "2.13"}, 20))
+	require.NotEqual(t, key, inlineKey([]string{"бхагавад-гита", "2.13"}, 0))
+
+	_, ok := c.get(key)
+	require.False(t, ok)
+
+	msgs := []domain.Message{{MediaID: 7}}
+	c.put(key, msgs)
+	got, ok := c.get(key)
+	require.True(t, ok)
+	require.Equal(t, msgs, got)
+
+	now = now.Add(time.Minute)
+	_, ok = c.get(key)
+	require.False(t, ok) // expired
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+const (
+	deliveryQueueSize = 100 // published messages waiting for subscribers, Enqueue blocks when queue is full
+	recentMediaSize   = 64  // media which subscribers are remembered for, so media of several tags is sent once
+)
+
+// SubscriptionStore is storage of subscriptions of listeners to tags, see database.Tgdb.
+type SubscriptionStore interface {
+	AddSubscription(ctx context.Context, s domain.Subscription) (bool, error)
+	DeleteSubscription(ctx context.Context, s domain.Subscription) (bool, error)
+	DeleteUserSubscriptions(ctx context.Context, configID int, userID int64) error
+	ListUserSubscriptions(ctx context.Context, configID int, userID int64) ([]string, error)
+	ListSubscribers(ctx context.Context, configID, tagID int) ([]domain.Subscription, error)
+}
+
+// blockedErrors are returned when bot can't send message to user anymore.
+var blockedErrors = []string{"USER_IS_BLOCKED", "INPUT_USER_DEACTIVATED", "USER_DEACTIVATED", "PEER_ID_INVALID"}
+
+// subscribe subscribes user to tag or unsubscribes from it, text of answer is returned.
+func (b *Bot) subscribe(ctx context.Context, e tg.Entities, userID int64, subscribe bool, tag string) (string, error) {
+	tag = strings.TrimPrefix(tag, "#")
+	tagID, err := b.store.GetTagByName(ctx, tag)
+	if errors.Is(err, database.ErrTagNotFound) {
+		return fmt.Sprintf("Tag '%s' not found.", tag), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	s := domain.Subscription{ConfigID: b.params.ConfigID, UserID: userID, TagID: tagID}
+
+	if !subscribe {
+		ok, err := b.store.DeleteSubscription(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("You are not subscribed to '%s'.", tag), nil
+		}
+		return fmt.Sprintf("Unsubscribed from '%s'.", tag), nil
+	}
+
+	user, ok := e.Users[userID]
+	if !ok {
+		return "", fmt.Errorf("user %d not found in update", userID)
+	}
+	s.AccessHash = user.AccessHash
+
+	ok, err = b.store.AddSubscription(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("You are already subscribed to '%s'.", tag), nil
+	}
+	b.log.Info().Int64("user", userID).Str("tag", tag).Msg("subscribed")
+	return fmt.Sprintf("Subscribed to '%s', new media will be sent here.", tag), nil
+}
+
+// subscriptions returns text with tags user is subscribed to.
+func (b *Bot) subscriptions(ctx context.Context, userID int64) (string, error) {
+	tags, err := b.store.ListUserSubscriptions(ctx, b.params.ConfigID, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "You have no subscriptions, see /subscribe <tag>.", nil
+	}
+	return "Your subscriptions:\n" + strings.Join(tags, "\n"), nil
+}
+
+// delivery is published message waiting for subscribers of tag.
+type delivery struct {
+	tagID int
+	msg   domain.Message
+}
+
+// Enqueue adds published message to queue of delivery to subscribers of tag, so
+// publishing doesn't wait for them. Messages are sent by Run in order of publishing,
+// Enqueue blocks while queue is full.
+func (b *Bot) Enqueue(ctx context.Context, tagID int, m domain.Message) error {
+	select {
+	case b.deliveries <- delivery{tagID: tagID, msg: m}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run sends messages of Enqueue to subscribers until ctx is done. It must be called in
+// running session, messages left in queue are not sent.
+func (b *Bot) Run(ctx context.Context) {
+	for {
+		select {
+		case dl := <-b.deliveries:
+			if err := b.deliver(ctx, dl.tagID, dl.msg); err != nil && ctx.Err() == nil {
+				b.log.Error().Err(err).Str("title", dl.msg.Title).Msg("send media to subscribers")
+			}
+		case <-ctx.Done():
+			if n := len(b.deliveries); n > 0 {
+				b.log.Warn().Int("messages", n).Msg("session closed, media are not sent to subscribers")
+			}
+			return
+		}
+	}
+}
+
+// deliver sends document of published message to subscribers of tag. Messages are sent
+// not faster than config.Bot.SubscriptionRate, subscriptions of users who blocked bot are deleted.
+// Users subscribed to several tags of media get it once.
+func (b *Bot) deliver(ctx context.Context, tagID int, m domain.Message) error {
+	subs, err := b.store.ListSubscribers(ctx, b.params.ConfigID, tagID)
+	if err != nil {
+		return err
+	}
+
+	sent, skipped := 0, 0
+	for _, s := range subs {
+		if b.recent.sent(m.MediaID, s.UserID) {
+			skipped++ // got media of other tag
+			continue
+		}
+		if err := b.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("wait for subscription rate limit: %w", err)
+		}
+
+		peer := &tg.InputPeerUser{UserID: s.UserID, AccessHash: s.AccessHash}
+		_, err := b.sender.To(peer).Media(ctx, message.Document(m.Document, styling.Plain(m.Title)))
+		switch {
+		case err == nil:
+			sent++
+			b.recent.add(m.MediaID, s.UserID) // media of other tags is sent again if sending failed
+		case tgerr.Is(err, blockedErrors...):
+			b.log.Info().Err(err).Int64("user", s.UserID).Msg("user blocked bot, subscriptions deleted")
+			if err := b.store.DeleteUserSubscriptions(ctx, b.params.ConfigID, s.UserID); err != nil {
+				b.log.Error().Err(err).Int64("user", s.UserID).Msg("delete subscriptions")
+			}
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			b.log.Warn().Err(err).Int64("user", s.UserID).Str("title", m.Title).Msg("send media to subscriber")
+		}
+	}
+
+	if len(subs) > 0 {
+		b.log.Info().Int("subscribers", len(subs)).Int("sent", sent).Int("skipped", skipped).Str("title", m.Title).Msg("media sent to subscribers")
+	}
+	return nil
+}
+
+// recentMedia remembers users which recent media were sent to. It's used by Run only,
+// so it's not locked. Media of several tags is published in a row, and it's forgotten
+// after restart.
+type recentMedia struct {
+	users map[int]map[int64]bool // by media ID
+	order []int                  // media IDs, the oldest first
+}
+
+func newRecentMedia() *recentMedia {
+	return &recentMedia{users: make(map[int]map[int64]bool)}
+}
+
+// sent reports if media was sent to user.
+func (r *recentMedia) sent(mediaID int, userID int64) bool {
+	return r.users[mediaID][userID]
+}
+
+// add remembers media sent to user.
+func (r *recentMedia) add(mediaID int, userID int64) {
+	users, ok := r.users[mediaID]
+	if !ok {
+		if len(r.order) == recentMediaSize {
+			delete(r.users, r.order[0])
+			r.order = r.order[1:]
+		}
+		users = make(map[int64]bool)
+		r.users[mediaID] = users
+		r.order = append(r.order, mediaID)
+	}
+	users[userID] = true
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gotd/td/tg"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/database"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+type subscriptionStore struct {
+	Store
+	subs   map[domain.Subscription]bool // without access hash
+	hashes map[int64]int64              // access hash by user
+}
+
+func (s *subscriptionStore) GetTagByName(_ context.Context, name string) (int, error) {
+	if name != "Гита" {
+		return 0, database.ErrTagNotFound
+	}
+	return 3, nil
+}
+
+func (s *subscriptionStore) AddSubscription(_ context.Context, sub domain.Subscription) (bool, error) {
+	s.hashes[sub.UserID] = sub.AccessHash
+	sub.AccessHash = 0
+	if s.subs[sub] {
+		return false, nil
+	}
+	s.subs[sub] = true
+	return true, nil
+}
+
+func (s *subscriptionStore) DeleteSubscription(_ context.Context, sub domain.Subscription) (bool, error) {
+	if !s.subs[sub] {
+		return false, nil
+	}
+	delete(s.subs, sub)
+	return true, nil
+}
+
+func (s *subscriptionStore) ListUserSubscriptions(_ context.Context, configID int, userID int64) ([]string, error) {
+	var tags []string
+	for sub := range s.subs {
+		if sub.ConfigID == configID && sub.UserID == userID {
+			tags = append(tags, "Гита")
+		}
+	}
+	return tags, nil
+}
+
+func TestSubscribe(t *testing.T) {
+	store := &subscriptionStore{subs: make(map[domain.Subscription]bool), hashes: make(map[int64]int64)}
+	b := &Bot{store: store, params: Params{ConfigID: 1}, log: zerolog.Nop()}
+	e := tg.Entities{Users: map[int64]*tg.User{10: {ID: 10, AccessHash: 20}}}
+	ctx := context.Background()
+
+	text, err := b.subscriptions(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, "You have no subscriptions, see /subscribe <tag>.", text)
+
+	text, err = b.subscribe(ctx, e, 10, true, "#Гита")
+	require.NoError(t, err)
+	require.Equal(t, "Subscribed to 'Гита', new media will be sent here.", text)
+	require.True(t, store.subs[domain.Subscription{ConfigID: 1, UserID: 10, TagID: 3}])
+	require.Equal(t, int64(20), store.hashes[10])
+
+	text, err = b.subscribe(ctx, e, 10, true, "Гита")
+	require.NoError(t, err)
+	require.Equal(t, "You are already subscribed to 'Гита'.", text)
+
+	text, err = b.subscriptions(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, "Your subscriptions:\nГита", text)
+
+	text, err = b.subscribe(ctx, e, 10, false, "Гита")
+	require.NoError(t, err)
+	require.Equal(t, "Unsubscribed from 'Гита'.", text)
+
+	text, err = b.subscribe(ctx, e, 10, false, "Гита")
+	require.NoError(t, err)
+	require.Equal(t, "You are not subscribed to 'Гита'.", text)
+
+	text, err = b.subscribe(ctx, e, 10, true, "Веды")
+	require.NoError(t, err)
+	require.Equal(t, "Tag 'Веды' not found.", text)
+
+	_, err = b.subscribe(ctx, tg.Entities{}, 10, true, "Гита")
+	require.Error(t, err)
+}
+
+func TestRecentMedia(t *testing.T) {
+	r := newRecentMedia()
+	require.False(t, r.sent(1, 10))
+	r.add(1, 10)
+	require.True(t, r.sent(1, 10)) // the same media of other tag
+	require.False(t, r.sent(1, 11))
+
+	for id := 2; id <= recentMediaSize; id++ {
+		r.add(id, 10)
+	}
+	require.True(t, r.sent(1, 10))
+
+	r.add(recentMediaSize+1, 10)
+	require.False(t, r.sent(1, 10)) // forgotten
+}
+
+func TestEnqueue(t *testing.T) {
+	b := &Bot{deliveries: make(chan delivery, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, b.Enqueue(ctx, 3, domain.Message{MediaID: 1}))
+	cancel()
+	require.ErrorIs(t, b.Enqueue(ctx, 3, domain.Message{MediaID: 2}), context.Canceled) // queue is full
+	require.Equal(t, delivery{tagID: 3, msg: domain.Message{MediaID: 1}}, <-b.deliveries)
+}
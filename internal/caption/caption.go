@@ -0,0 +1,46 @@
+// Package caption renders captions of published media from text/template.
+package caption
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// Default is caption with title and hashtags of media, hashtag of topic tag is first:
+//
+//	Title
+//	#Tag #OtherTag
+//
+// Hashtags of other tags are added if media is posted once, see domain.MultiTagForward.
+// Template data is domain.Audio, e.g. {{.Title}}, {{.Teaser}}, {{.Performer}}, {{.HashTag}},
+// {{.HashTags}}, {{.OccurrenceDate.Format "02.01.2006"}}.
+const Default = "{{.Title}}\n{{.HashTags}}"
+
+// Parse parses caption template. Empty text gives Default template.
+func Parse(text string) (*template.Template, error) {
+	if text == "" {
+		text = Default
+	}
+	t, err := template.New("caption").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse caption template: %w", err)
+	}
+	return t, nil
+}
+
+// Render returns caption of audio rendered with template text.
+func Render(text string, a domain.Audio) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, a); err != nil {
+		return "", fmt.Errorf("render caption of '%s': %w", a.Title, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
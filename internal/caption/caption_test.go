@@ -0,0 +1,73 @@
+package caption
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestRender(t *testing.T) {
+	teaser := "О преданном служении"
+	a := domain.Audio{
+		Title:          "Бхагавад-гита 2.47",
+		Teaser:         &teaser,
+		Tag:            "Бхагавад-гита",
+		Performer:      "Reader of classes",
+		OccurrenceDate: time.Date(2025, time.August, 24, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Default",
+			want: "Бхагавад-гита 2.47\n#Бхагавад_гита",
+		},
+		{
+			name: "Custom",
+			text: `{{.OccurrenceDate.Format "02.01.2006"}} {{.Title}}{{with .Teaser}}
+{{.}}{{end}}
+{{.Performer}} {{.HashTag}}`,
+			want: "24.08.2025 Бхагавад-гита 2.47\nО преданном служении\nReader of classes #Бхагавад_гита",
+		},
+		{
+			name:    "Unknown Field",
+			text:    "{{.Subtitle}}",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Template",
+			text:    "{{.Title",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.text, a)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRender_Tags(t *testing.T) {
+	a := domain.Audio{
+		Title: "Бхагавад-гита 2.47",
+		Tag:   "Бхагавад-гита",
+		Tags:  []string{"Бхагавад-гита", "Праздники"},
+	}
+
+	got, err := Render("", a)
+	require.NoError(t, err)
+	require.Equal(t, "Бхагавад-гита 2.47\n#Бхагавад_гита #Праздники", got)
+}
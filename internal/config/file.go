@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// WritePeer sets telegram.group_id, telegram.mtproto_group_id and telegram.access_hash
+// of resolved peer in config file, other settings and comments are kept.
+func WritePeer(path string, p domain.Peer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errors.New("config file must be mapping")
+	}
+
+	telegram := mappingValue(root, "telegram")
+	if telegram.Kind != yaml.MappingNode {
+		return errors.New("telegram section of config file must be mapping")
+	}
+	setScalar(telegram, "group_id", strconv.FormatInt(p.ChatID(), 10))
+	setScalar(telegram, "mtproto_group_id", strconv.FormatInt(p.ChannelID, 10))
+	setScalar(telegram, "access_hash", strconv.FormatInt(p.AccessHash, 10))
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	return os.WriteFile(path, b.Bytes(), info.Mode().Perm())
+}
+
+// mappingValue returns value of key in mapping node, empty mapping is added if key is absent.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// setScalar sets integer value of key in mapping node, comments of key are kept.
+func setScalar(m *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			v.Kind, v.Tag, v.Value, v.Style = yaml.ScalarNode, "!!int", value, 0
+			return
+		}
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestWritePeer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tg.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# bot settings
+telegram:
+  bot_token: 1234567890:AAAA
+  group_id: -1001234567890
+  access_hash: 0 # see tg resolve
+server:
+  chunk_size: 30
+`), 0o600))
+
+	require.NoError(t, WritePeer(path, domain.Peer{ChannelID: 2586736000, AccessHash: -6294104672070874117}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "# bot settings")
+	require.Contains(t, string(data), "access_hash: -6294104672070874117 # see tg resolve")
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	require.NoError(t, v.ReadInConfig())
+	require.Equal(t, int64(-1002586736000), v.GetInt64("telegram.group_id"))
+	require.Equal(t, int64(2586736000), v.GetInt64("telegram.mtproto_group_id"))
+	require.Equal(t, int64(-6294104672070874117), v.GetInt64("telegram.access_hash"))
+	require.Equal(t, "1234567890:AAAA", v.GetString("telegram.bot_token"))
+	require.Equal(t, 30, v.GetInt("server.chunk_size"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.Error(t, WritePeer(filepath.Join(t.TempDir(), "missing.yaml"), domain.Peer{}))
+}
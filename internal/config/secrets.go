@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretKeys are config keys with credentials. Their values are masked in logs and
+// can be set by environment variables, e.g. TG_TELEGRAM_BOT_TOKEN, or read from
+// files set by key with "_file" suffix, e.g. telegram.bot_token_file or TG_TELEGRAM_BOT_TOKEN_FILE.
+var SecretKeys = []string{
+	"telegram.bot_token",
+	"telegram.app_hash",
+	"database.dsn",
+	"notify.bot_token",
+	"notify.webhook",
+	"proxy.url",
+	"proxy.secret",
+}
+
+// secretNames are parts of key names which values are always masked.
+var secretNames = []string{"token", "password", "secret"}
+
+const (
+	EnvPrefix  = "TG"
+	fileSuffix = "_file"
+	mask       = "******"
+)
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// InitEnv makes viper read config keys from environment variables with TG_ prefix,
+// e.g. TG_DATABASE_DSN for database.dsn.
+func InitEnv(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+}
+
+// LoadSecrets binds environment variables of secrets and reads secrets from files.
+// Secret from file overrides value from config file and environment.
+func LoadSecrets(v *viper.Viper) error {
+	for _, key := range SecretKeys {
+		if err := v.BindEnv(key); err != nil {
+			return fmt.Errorf("bind env of %s: %w", key, err)
+		}
+		if err := v.BindEnv(key + fileSuffix); err != nil {
+			return fmt.Errorf("bind env of %s: %w", key+fileSuffix, err)
+		}
+
+		path := v.GetString(key + fileSuffix)
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", key+fileSuffix, err)
+		}
+		v.Set(key, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
+
+// Redact returns copy of settings (see viper.AllSettings) with masked secrets.
+func Redact(settings map[string]any) map[string]any {
+	return redact("", settings)
+}
+
+func redact(prefix string, settings map[string]any) map[string]any {
+	res := make(map[string]any, len(settings))
+	for k, val := range settings {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch val := val.(type) {
+		case map[string]any:
+			res[k] = redact(key, val)
+		case string:
+			res[k] = val
+			if key == "database.dsn" {
+				res[k] = RedactDSN(val)
+			} else if isSecret(key) && val != "" {
+				res[k] = mask
+			}
+		default:
+			res[k] = val
+			if isSecret(key) {
+				res[k] = mask
+			}
+		}
+	}
+	return res
+}
+
+func isSecret(key string) bool {
+	if strings.HasSuffix(key, fileSuffix) {
+		return false // path to file with secret
+	}
+	for _, k := range SecretKeys {
+		if key == k {
+			return true
+		}
+	}
+	name := key[strings.LastIndex(key, ".")+1:]
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactDSN masks password in postgresql connection string in URL or key=value format.
+func RedactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), mask)
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", mask)
+			u.RawQuery = q.Encode()
+		}
+		// keep mask readable, url encoding is not needed in logs
+		return strings.ReplaceAll(u.String(), url.QueryEscape(mask), mask)
+	}
+
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+mask)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,6 +17,12 @@ import (
 
 var ErrEmptyQueue = errors.New("media to publish not found")
 var ErrNoSingleInstance = errors.New("no single instance data for this media")
+var ErrNotInQueue = errors.New("item not found in queue")
+var ErrTagNotFound = errors.New("tag not found")
+var ErrConfigNotFound = errors.New("config not found")
+var ErrTopicNotFound = errors.New("topic not found")
+var ErrDestinationNotFound = errors.New("destination not found")
+var ErrMessageNotFound = errors.New("message not found")
 
 type Tgdb struct {
 	pool    *pgxpool.Pool
@@ -39,7 +47,13 @@ func New(urn string) (Tgdb, error) {
 
 func (d *Tgdb) connect(ctx context.Context, urn string) error {
 
-	pool, err := pgxpool.New(ctx, urn)
+	pc, err := pgxpool.ParseConfig(urn)
+	if err != nil {
+		return fmt.Errorf("parse connection string: %w", err)
+	}
+	pc.ConnConfig.Tracer = queryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pc)
 	if err != nil {
 		return fmt.Errorf("create connection pool: %w", err)
 	}
@@ -86,25 +100,64 @@ func (d *Tgdb) ListAllTopics(ctx context.Context) ([]domain.Topic, error) {
 		return nil, fmt.Errorf("list all topics: %w", err)
 	}
 
-	return genTopics(topics), nil
+	return genTopics(topics)
+}
+
+func (d *Tgdb) SetTopicDelivery(ctx context.Context, ID uint64, p domain.DeliveryPolicy) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal delivery policy: %w", err)
+	}
+
+	if err := d.queries.SetTopicDelivery(ctx, gen.SetTopicDeliveryParams{
+		Delivery: b,
+		ID:       ID,
+	}); err != nil {
+		return fmt.Errorf("set topic delivery policy: %w", err)
+	}
+	return nil
+}
+
+// GetQueueStats returns size of queue and failed queue of topics of config, configID 0 - of all topics.
+func (d *Tgdb) GetQueueStats(ctx context.Context, configID int) (domain.QueueStats, error) {
+	st, err := d.queries.GetQueueStats(ctx, optionalID(configID))
+	if err != nil {
+		return domain.QueueStats{}, fmt.Errorf("get queue stats: %w", err)
+	}
+	return domain.QueueStats{Queue: st.Queue, Failed: st.Failed}, nil
 }
 
-func (d *Tgdb) ListMediaQueue(ctx context.Context, limit int32, cursor uint64) ([]domain.Audio, uint64, error) {
+// ListMediaQueue returns head of queue in order of priority and occurrence date.
+// Queue items with IDs from exclude are skipped, they are already being processed.
+// Only topics of config with configID are listed, 0 lists queue of all topics.
+func (d *Tgdb) ListMediaQueue(ctx context.Context, configID int, limit int32, exclude []uint64) ([]domain.Audio, error) {
+	ids := make([]int, 0, len(exclude))
+	for _, id := range exclude {
+		ids = append(ids, int(id))
+	}
+
 	audioToPublish, err := d.queries.ListMediaQueue(ctx, gen.ListMediaQueueParams{
-		ID:    cursor, // tg_queue.id is a cursor
-		Limit: limit,
+		Exclude:  ids,
+		ConfigID: optionalID(configID),
+		Limit:    limit,
 	})
 	if err != nil {
-		return nil, 0, fmt.Errorf("list queue to publish: %w", err)
+		return nil, fmt.Errorf("list queue to publish: %w", err)
 	}
 	if len(audioToPublish) == 0 {
-		return nil, 0, ErrEmptyQueue
+		return nil, ErrEmptyQueue
 	}
 
 	res := make([]domain.Audio, 0, len(audioToPublish))
 	for _, a := range audioToPublish {
+		delivery, err := genDelivery(a.Delivery)
+		if err != nil {
+			return nil, fmt.Errorf("topic of '%s': %w", a.Title, err)
+		}
 
 		res = append(res, domain.Audio{
+			QueueID: a.ID,
+			Queued:  a.Queued,
 			MediaID: a.MediaID,
 			Title:   a.Title,
 			Teaser:  a.Teaser,
@@ -115,38 +168,158 @@ func (d *Tgdb) ListMediaQueue(ctx context.Context, limit int32, cursor uint64) (
 				return ""
 			}(),
 			MessageThreadID: a.MessageThreadID,
+			TopicID:         uint64(a.TopicID),
 			TagID:           a.TagID,
 			Tag:             a.Tag,
 			OccurrenceDate:  a.OccurrenceDate,
 			IssueDate:       a.IssueDate,
 			Duration:        a.Duration,
 			Size:            a.Size,
+			Delivery:        delivery,
 		})
 	}
 
-	return res, audioToPublish[len(audioToPublish)-1].Cursor, nil
+	return res, nil
 }
 
-func (d *Tgdb) AddAudioToFailedQueue(ctx context.Context, a domain.Audio, err error) error {
-	if err := d.queries.AddMediaToFailedQueue(ctx, gen.AddMediaToFailedQueueParams{
-		Error:           err.Error(),
-		MediaID:         a.MediaID,
-		MessageThreadID: a.MessageThreadID,
-		TagID:           a.TagID,
-	}); err != nil {
-		return fmt.Errorf("add audio to failed queue: %w", err)
+// ListQueue returns queued media in order of publishing. If configID is 0 queue of all topics is returned.
+func (d *Tgdb) ListQueue(ctx context.Context, configID int, limit int32) ([]domain.QueueItem, error) {
+	rows, err := d.queries.ListQueue(ctx, gen.ListQueueParams{
+		ConfigID: optionalID(configID),
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list queue: %w", err)
+	}
+
+	res := make([]domain.QueueItem, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, domain.QueueItem{
+			Priority: r.Priority,
+			Topic:    r.Topic,
+			Audio: domain.Audio{
+				QueueID: r.ID,
+				MediaID: r.MediaID,
+				Title:   r.Title,
+				Path: func() string {
+					if r.FileUrl != nil {
+						return *r.FileUrl
+					}
+					return ""
+				}(),
+				Tag:            r.Tag,
+				OccurrenceDate: r.OccurrenceDate,
+			},
+		})
 	}
+	return res, nil
+}
 
+// AddMediaToQueue adds media to queue of each topic linked to media tags.
+// If tagID is 0 all media tags are used. Returns number of added queue items.
+func (d *Tgdb) AddMediaToQueue(ctx context.Context, mediaID, tagID, priority int) (int64, error) {
+	n, err := d.queries.AddMediaToQueue(ctx, gen.AddMediaToQueueParams{
+		Priority: priority,
+		MediaID:  mediaID,
+		TagID:    optionalID(tagID),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("add media to queue: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteFromQueue removes item from queue of topics of config. If configID is 0 item of any topic is removed.
+func (d *Tgdb) DeleteFromQueue(ctx context.Context, configID int, ID uint64) error {
+	n, err := d.queries.DeleteFromQueue(ctx, gen.DeleteFromQueueParams{ID: ID, ConfigID: optionalID(configID)})
+	if err != nil {
+		return fmt.Errorf("delete from queue: %w", err)
+	}
+	if n == 0 {
+		return ErrNotInQueue
+	}
 	return nil
 }
 
-func (d *Tgdb) RemoveFromQueue(ctx context.Context, mediaID, tagID int) error {
-	if err := d.queries.RemoveMediaQueue(ctx, gen.RemoveMediaQueueParams{
-		MediaID: mediaID,
-		TagID:   tagID,
+// ClearQueue removes all items with tag from queue of topics of config. If tagID is 0 all items
+// are removed, if configID is 0 queue of all topics is cleared.
+func (d *Tgdb) ClearQueue(ctx context.Context, configID, tagID int) (int64, error) {
+	n, err := d.queries.ClearQueue(ctx, gen.ClearQueueParams{TagID: optionalID(tagID), ConfigID: optionalID(configID)})
+	if err != nil {
+		return 0, fmt.Errorf("clear queue: %w", err)
+	}
+	return n, nil
+}
+
+// PrioritizeQueue moves item of topics of config to the head of queue. If configID is 0 item of any topic is moved.
+func (d *Tgdb) PrioritizeQueue(ctx context.Context, configID int, ID uint64) error {
+	n, err := d.queries.PrioritizeQueue(ctx, gen.PrioritizeQueueParams{ID: ID, ConfigID: optionalID(configID)})
+	if err != nil {
+		return fmt.Errorf("prioritize queue item: %w", err)
+	}
+	if n == 0 {
+		return ErrNotInQueue
+	}
+	return nil
+}
+
+// RetryFailed moves media of topics of config from failed queue back to queue.
+// configID 0 - failed media of all topics. Returns number of added queue items.
+func (d *Tgdb) RetryFailed(ctx context.Context, configID, priority int) (int64, error) {
+	n, err := d.queries.RetryFailed(ctx, gen.RetryFailedParams{
+		ConfigID: optionalID(configID),
+		Priority: priority,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("retry failed media: %w", err)
+	}
+	return n, nil
+}
+
+func (d *Tgdb) GetTagByName(ctx context.Context, name string) (int, error) {
+	t, err := d.queries.GetTagByName(ctx, name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrTagNotFound
+		}
+		return 0, fmt.Errorf("get tag by name: %w", err)
+	}
+	return t.ID, nil
+}
+
+// TagGetter finds ID of tag by its name, see Tgdb.GetTagByName.
+type TagGetter interface {
+	GetTagByName(ctx context.Context, name string) (int, error)
+}
+
+// ResolveTag returns tag ID by its name or ID. Name is looked up first, so tag
+// with numeric name is found by name. Empty tag gives 0.
+func ResolveTag(ctx context.Context, g TagGetter, tag string) (int, error) {
+	if tag == "" {
+		return 0, nil
+	}
+
+	id, err := g.GetTagByName(ctx, tag)
+	if errors.Is(err, ErrTagNotFound) {
+		if id, err := strconv.Atoi(tag); err == nil {
+			return id, nil
+		}
+		return 0, fmt.Errorf("tag %q: %w", tag, err)
+	}
+	return id, err
+}
+
+func (d *Tgdb) AddAudioToFailedQueue(ctx context.Context, a domain.Audio, err error) error {
+	if err := d.queries.AddMediaToFailedQueue(ctx, gen.AddMediaToFailedQueueParams{
+		TopicID: a.TopicID,
+		MediaID: a.MediaID,
+		TagID:   a.TagID,
+		Error:   err.Error(),
+		Queued:  optionalTime(a.Queued),
 	}); err != nil {
-		return fmt.Errorf("remove id from queue: %w", err)
+		return fmt.Errorf("add audio to failed queue: %w", err)
 	}
+
 	return nil
 }
 
@@ -160,26 +333,103 @@ func (d *Tgdb) MakeTopicPublished(ctx context.Context, MessageThreadID int, ID u
 	return nil
 }
 
-// INFO: For future use when will be many chatbots on one server
+// GetConfig returns bot config by slug.
 func (d *Tgdb) GetConfig(ctx context.Context, slug string) (*domain.Config, error) {
 	cfg, err := d.queries.GetConfig(ctx, slug)
 	if err != nil {
-		return nil, fmt.Errorf("mark topic as published: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, slug)
+		}
+		return nil, fmt.Errorf("get config: %w", err)
 	}
 
-	settings := domain.BotSettings{UploadThreads: 2}
-	if cfg.Settings != nil {
-		err = json.Unmarshal(cfg.Settings, &settings)
+	return genConfig(cfg)
+}
+
+// SetConfigPaused pauses or resumes publishing of all topics of config.
+func (d *Tgdb) SetConfigPaused(ctx context.Context, slug string, paused bool) error {
+	n, err := d.queries.SetConfigPaused(ctx, gen.SetConfigPausedParams{Paused: paused, Slug: slug})
+	if err != nil {
+		return fmt.Errorf("set config paused: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, slug)
+	}
+	return nil
+}
+
+// SetPaused pauses or resumes publishing of all topics, topics without config too.
+// Resume also resumes configs paused by SetConfigPaused, topics paused by SetTopicPaused stay paused.
+func (d *Tgdb) SetPaused(ctx context.Context, paused bool) error {
+	if err := d.queries.SetPaused(ctx, paused); err != nil {
+		return fmt.Errorf("set paused: %w", err)
+	}
+	return nil
+}
+
+// IsPaused reports if publishing of all topics is paused by SetPaused.
+func (d *Tgdb) IsPaused(ctx context.Context) (bool, error) {
+	paused, err := d.queries.IsPaused(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get pause: %w", err)
+	}
+	return paused, nil
+}
+
+// SetConfigPeer saves group of resolved peer to settings of config, other settings are kept.
+func (d *Tgdb) SetConfigPeer(ctx context.Context, slug string, p domain.Peer) error {
+	n, err := d.queries.SetConfigPeer(ctx, gen.SetConfigPeerParams{
+		GroupID:        int(p.ChatID()),
+		MtprotoGroupID: int(p.ChannelID),
+		AccessHash:     int(p.AccessHash),
+		Slug:           slug,
+	})
+	if err != nil {
+		return fmt.Errorf("set config peer: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, slug)
+	}
+	return nil
+}
+
+// SetTopicPaused pauses or resumes publishing of topic, other topics are not affected.
+func (d *Tgdb) SetTopicPaused(ctx context.Context, ID uint64, paused bool) error {
+	n, err := d.queries.SetTopicPaused(ctx, gen.SetTopicPausedParams{Paused: paused, ID: ID})
+	if err != nil {
+		return fmt.Errorf("set topic paused: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %d", ErrTopicNotFound, ID)
+	}
+	return nil
+}
+
+// IsTopicPaused reports if publishing of topic, its config or all topics is paused.
+func (d *Tgdb) IsTopicPaused(ctx context.Context, ID uint64) (bool, error) {
+	paused, err := d.queries.IsTopicPaused(ctx, ID)
+	if err != nil {
+		return false, fmt.Errorf("get topic pause: %w", err)
+	}
+	return paused, nil
+}
+
+// ListConfigs returns configs of all bots served by one deployment.
+func (d *Tgdb) ListConfigs(ctx context.Context) ([]domain.Config, error) {
+	configs, err := d.queries.ListConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list configs: %w", err)
+	}
+
+	res := make([]domain.Config, 0, len(configs))
+	for _, c := range configs {
+		cfg, err := genConfig(c)
 		if err != nil {
-			return nil, fmt.Errorf("unmarshal settings: %w", err)
+			return nil, err
 		}
+		res = append(res, *cfg)
 	}
-
-	return &domain.Config{
-		Slug:             cfg.Slug,
-		RecentUploadTime: cfg.RecentUploadTime,
-		Settings:         settings,
-	}, nil
+	return res, nil
 }
 
 // link media to telegragm audio. This is important to make single instance storage for audio files.
@@ -194,22 +444,73 @@ func (d *Tgdb) LinkMediaToTelegram(ctx context.Context, MediaID int, TgAudioID s
 	return nil
 }
 
-func (d *Tgdb) PopulateMedia(ctx context.Context, t time.Time, tagID int) error {
-	if tagID == 0 {
-		if err := d.queries.PopulateMedia(ctx, t); err != nil {
-			return fmt.Errorf("populate database: %w", err)
-		}
-		return nil
+// PopulateFilter selects media to add to queue. Zero fields are not used for filtering.
+type PopulateFilter struct {
+	ConfigID      int       // tg_config.id of topics
+	Since         time.Time // occurrence date after
+	Until         time.Time // occurrence date till, inclusive
+	TagID         int
+	TopicID       int
+	MediaIDs      []int
+	SkipPublished bool // exclude media that already have telegram link
+}
+
+// PopulateMedia adds media matching filter to queue of their topics. Returns number of added queue items.
+func (d *Tgdb) PopulateMedia(ctx context.Context, f PopulateFilter, priority int) (int64, error) {
+	n, err := d.queries.PopulateQueue(ctx, gen.PopulateQueueParams{
+		Priority:      priority,
+		ConfigID:      optionalID(f.ConfigID),
+		Since:         optionalTime(f.Since),
+		Until:         optionalTime(f.Until),
+		TagID:         optionalID(f.TagID),
+		TopicID:       optionalID(f.TopicID),
+		MediaIds:      mediaIDs(f.MediaIDs),
+		SkipPublished: f.SkipPublished,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("populate database: %w", err)
 	}
 
-	if err := d.queries.PopulateMediaWithTagID(ctx, gen.PopulateMediaWithTagIDParams{
-		OccurrenceDate: t,
-		ID:             tagID,
-	}); err != nil {
-		return fmt.Errorf("populate database: %w", err)
+	return n, nil
+}
+
+// ListPopulateCandidates returns media that PopulateMedia would add to queue.
+func (d *Tgdb) ListPopulateCandidates(ctx context.Context, f PopulateFilter) ([]domain.QueueItem, error) {
+	rows, err := d.queries.ListPopulateCandidates(ctx, gen.ListPopulateCandidatesParams{
+		ConfigID:      optionalID(f.ConfigID),
+		Since:         optionalTime(f.Since),
+		Until:         optionalTime(f.Until),
+		TagID:         optionalID(f.TagID),
+		TopicID:       optionalID(f.TopicID),
+		MediaIds:      mediaIDs(f.MediaIDs),
+		SkipPublished: f.SkipPublished,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list populate candidates: %w", err)
 	}
 
-	return nil
+	res := make([]domain.QueueItem, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, domain.QueueItem{
+			Topic:     r.Topic,
+			Published: r.Published,
+			Audio: domain.Audio{
+				MediaID: r.MediaID,
+				Title:   r.Title,
+				Path: func() string {
+					if r.FileUrl != nil {
+						return *r.FileUrl
+					}
+					return ""
+				}(),
+				TopicID:        r.TopicID,
+				TagID:          r.TagID,
+				Tag:            r.Tag,
+				OccurrenceDate: r.OccurrenceDate,
+			},
+		})
+	}
+	return res, nil
 }
 
 func (d *Tgdb) GetSingleInstanceAudio(ctx context.Context, mediaID int) (*string, error) {
@@ -223,16 +524,322 @@ func (d *Tgdb) GetSingleInstanceAudio(ctx context.Context, mediaID int) (*string
 	return &r.Value, nil
 }
 
-func genTopics(topics []gen.ListAllTopicsRow) []domain.Topic {
+// AddMessage saves published message with document of media.
+func (d *Tgdb) AddMessage(ctx context.Context, m domain.Message) error {
+	if err := d.queries.AddMessage(ctx, gen.AddMessageParams{
+		TopicID:       int(m.TopicID),
+		MediaID:       m.MediaID,
+		MessageID:     m.MessageID,
+		DocumentID:    int(m.Document.ID),
+		AccessHash:    int(m.Document.AccessHash),
+		FileReference: m.Document.FileReference,
+		OriginID:      optionalID(int(m.OriginID)),
+	}); err != nil {
+		return fmt.Errorf("add published message: %w", err)
+	}
+	return nil
+}
+
+// GetOriginMessage returns message with media posted in topics of the same config as topic,
+// it's forwarded or sent again to topic instead of posting media once more. Message must be
+// posted since media was queued, so post of media queued again later isn't reused.
+func (d *Tgdb) GetOriginMessage(ctx context.Context, mediaID int, topicID uint64, queued time.Time) (domain.Message, error) {
+	r, err := d.queries.GetOriginMessage(ctx, gen.GetOriginMessageParams{
+		MediaID: mediaID,
+		Queued:  queued,
+		TopicID: topicID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Message{}, ErrMessageNotFound
+	}
+	if err != nil {
+		return domain.Message{}, fmt.Errorf("get origin message: %w", err)
+	}
+	return domain.Message{
+		ID:        r.ID,
+		MediaID:   mediaID,
+		TopicID:   uint64(r.TopicID),
+		MessageID: r.MessageID,
+		Document: domain.Document{
+			ID:            int64(r.DocumentID),
+			AccessHash:    int64(r.AccessHash),
+			FileReference: r.FileReference,
+		},
+	}, nil
+}
+
+// ListMediaTags returns names of tags of media which have topics of the same config as topic.
+func (d *Tgdb) ListMediaTags(ctx context.Context, mediaID int, topicID uint64) ([]string, error) {
+	tags, err := d.queries.ListMediaTags(ctx, gen.ListMediaTagsParams{
+		MediaID: mediaID,
+		TopicID: topicID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list tags of media: %w", err)
+	}
+	return tags, nil
+}
+
+// ListLatestMessages returns recently published messages of topics of config, newest first.
+// Messages are filtered by tag if it's not empty. configID 0 - messages of all topics.
+func (d *Tgdb) ListLatestMessages(ctx context.Context, configID int, tag string, limit int) ([]domain.Message, error) {
+	var t *string
+	if tag != "" {
+		t = &tag
+	}
+	rows, err := d.queries.ListLatestMessages(ctx, gen.ListLatestMessagesParams{
+		ConfigID: optionalID(configID),
+		Tag:      t,
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list latest messages: %w", err)
+	}
+
+	res := make([]domain.Message, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, genMessage(r))
+	}
+	return res, nil
+}
+
+// SearchMessages returns page of published messages of topics of config with media which title
+// or teaser contains all words, case insensitive. Media published in several topics is returned once,
+// without words all media are returned. configID 0 - messages of all topics.
+func (d *Tgdb) SearchMessages(ctx context.Context, configID int, words []string, limit, offset int) ([]domain.Message, error) {
+	rows, err := d.queries.SearchMessages(ctx, gen.SearchMessagesParams{
+		ConfigID: optionalID(configID),
+		Patterns: likePatterns(words),
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+
+	res := make([]domain.Message, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, genMessage(gen.ListLatestMessagesRow(r)))
+	}
+	return res, nil
+}
+
+// AddSubscription subscribes user to tag. It returns false if user is already subscribed.
+func (d *Tgdb) AddSubscription(ctx context.Context, s domain.Subscription) (bool, error) {
+	n, err := d.queries.AddSubscription(ctx, gen.AddSubscriptionParams{
+		ConfigID:   optionalID(s.ConfigID),
+		UserID:     int(s.UserID),
+		AccessHash: int(s.AccessHash),
+		TagID:      s.TagID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("add subscription: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteSubscription unsubscribes user from tag. It returns false if user isn't subscribed.
+func (d *Tgdb) DeleteSubscription(ctx context.Context, s domain.Subscription) (bool, error) {
+	n, err := d.queries.DeleteSubscription(ctx, gen.DeleteSubscriptionParams{
+		ConfigID: optionalID(s.ConfigID),
+		UserID:   int(s.UserID),
+		TagID:    s.TagID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete subscription: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteUserSubscriptions unsubscribes user from all tags, e.g. if user blocked bot.
+func (d *Tgdb) DeleteUserSubscriptions(ctx context.Context, configID int, userID int64) error {
+	if _, err := d.queries.DeleteUserSubscriptions(ctx, gen.DeleteUserSubscriptionsParams{
+		ConfigID: optionalID(configID),
+		UserID:   int(userID),
+	}); err != nil {
+		return fmt.Errorf("delete subscriptions of user: %w", err)
+	}
+	return nil
+}
+
+// ListUserSubscriptions returns names of tags user is subscribed to with bot of config.
+func (d *Tgdb) ListUserSubscriptions(ctx context.Context, configID int, userID int64) ([]string, error) {
+	tags, err := d.queries.ListUserSubscriptions(ctx, gen.ListUserSubscriptionsParams{
+		ConfigID: optionalID(configID),
+		UserID:   int(userID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list subscriptions of user: %w", err)
+	}
+	return tags, nil
+}
+
+// ListSubscribers returns subscriptions of users to tag with bot of config.
+func (d *Tgdb) ListSubscribers(ctx context.Context, configID, tagID int) ([]domain.Subscription, error) {
+	rows, err := d.queries.ListSubscribers(ctx, gen.ListSubscribersParams{
+		ConfigID: optionalID(configID),
+		TagID:    tagID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list subscribers: %w", err)
+	}
+
+	res := make([]domain.Subscription, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, domain.Subscription{
+			ConfigID:   configID,
+			UserID:     int64(r.UserID),
+			AccessHash: int64(r.AccessHash),
+			TagID:      tagID,
+		})
+	}
+	return res, nil
+}
+
+// AddDestination adds destination of topic, ID of destination is returned.
+func (d *Tgdb) AddDestination(ctx context.Context, dst domain.Destination) (uint64, error) {
+	var tmpl *string
+	if dst.CaptionTemplate != "" {
+		tmpl = &dst.CaptionTemplate
+	}
+	id, err := d.queries.AddDestination(ctx, gen.AddDestinationParams{
+		TopicID:         int(dst.TopicID),
+		Name:            dst.Name,
+		ChannelID:       int(dst.ChannelID),
+		AccessHash:      int(dst.AccessHash),
+		MessageThreadID: optionalID(dst.MessageThreadID),
+		CaptionTemplate: tmpl,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("add destination: %w", err)
+	}
+	return id, nil
+}
+
+// DeleteDestination deletes destination by ID.
+func (d *Tgdb) DeleteDestination(ctx context.Context, ID uint64) error {
+	n, err := d.queries.DeleteDestination(ctx, ID)
+	if err != nil {
+		return fmt.Errorf("delete destination: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %d", ErrDestinationNotFound, ID)
+	}
+	return nil
+}
+
+// ListDestinations returns destinations of topic, topicID 0 - destinations of all topics.
+func (d *Tgdb) ListDestinations(ctx context.Context, topicID uint64) ([]domain.Destination, error) {
+	rows, err := d.queries.ListDestinations(ctx, optionalID(int(topicID)))
+	if err != nil {
+		return nil, fmt.Errorf("list destinations: %w", err)
+	}
+
+	res := make([]domain.Destination, 0, len(rows))
+	for _, r := range rows {
+		dst := domain.Destination{
+			ID:         r.ID,
+			TopicID:    uint64(r.TopicID),
+			Topic:      r.Topic,
+			Name:       r.Name,
+			ChannelID:  int64(r.ChannelID),
+			AccessHash: int64(r.AccessHash),
+		}
+		if r.MessageThreadID != nil {
+			dst.MessageThreadID = *r.MessageThreadID
+		}
+		if r.CaptionTemplate != nil {
+			dst.CaptionTemplate = *r.CaptionTemplate
+		}
+		res = append(res, dst)
+	}
+	return res, nil
+}
+
+// likePatterns returns patterns of ilike which match text containing words.
+func likePatterns(words []string) []string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	patterns := make([]string, 0, len(words))
+	for _, w := range words {
+		patterns = append(patterns, "%"+r.Replace(w)+"%")
+	}
+	return patterns
+}
+
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func mediaIDs(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
+
+func genConfig(cfg gen.TgConfig) (*domain.Config, error) {
+	settings := domain.BotSettings{}
+	if cfg.Settings != nil {
+		if err := json.Unmarshal(cfg.Settings, &settings); err != nil {
+			return nil, fmt.Errorf("unmarshal settings of '%s': %w", cfg.Slug, err)
+		}
+	}
+
+	return &domain.Config{
+		ID:               int(cfg.ID),
+		Slug:             cfg.Slug,
+		RecentUploadTime: cfg.RecentUploadTime,
+		Settings:         settings,
+		Paused:           cfg.Paused,
+	}, nil
+}
+
+func genMessage(r gen.ListLatestMessagesRow) domain.Message {
+	return domain.Message{
+		MediaID:         r.MediaID,
+		Title:           r.Title,
+		TopicID:         r.TopicID,
+		MessageThreadID: r.MessageThreadID,
+		Tag:             r.Tag,
+		MessageID:       r.MessageID,
+		Document: domain.Document{
+			ID:            int64(r.DocumentID),
+			AccessHash:    int64(r.AccessHash),
+			FileReference: r.FileReference,
+		},
+		Created: r.Created,
+	}
+}
+
+func genTopics(topics []gen.ListAllTopicsRow) ([]domain.Topic, error) {
 	mTop := make([]domain.Topic, 0, len(topics))
 	for _, topic := range topics {
-		mTop = append(mTop, genTopic(topic))
+		t, err := genTopic(topic)
+		if err != nil {
+			return nil, err
+		}
+		mTop = append(mTop, t)
 	}
 
-	return mTop
+	return mTop, nil
 }
 
-func genTopic(topic gen.ListAllTopicsRow) domain.Topic {
+func genTopic(topic gen.ListAllTopicsRow) (domain.Topic, error) {
+	delivery, err := genDelivery(topic.Delivery)
+	if err != nil {
+		return domain.Topic{}, fmt.Errorf("topic '%s': %w", topic.Name, err)
+	}
+
 	return domain.Topic{
 		ID:                topic.ID,
 		MessageThreadID:   topic.MessageThreadID,
@@ -241,5 +848,24 @@ func genTopic(topic gen.ListAllTopicsRow) domain.Topic {
 		IconCustomEmojiID: topic.IconCustomEmojiID,
 		CreatedAt:         topic.Created,
 		Tag:               topic.Tag,
+		Delivery:          delivery,
+		ConfigID: func() int {
+			if topic.ConfigID == nil {
+				return 0
+			}
+			return *topic.ConfigID
+		}(),
+		Paused: topic.Paused,
+	}, nil
+}
+
+func genDelivery(raw json.RawMessage) (domain.DeliveryPolicy, error) {
+	var p domain.DeliveryPolicy
+	if len(raw) == 0 {
+		return p, nil
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, fmt.Errorf("unmarshal delivery policy: %w", err)
 	}
+	return p, nil
 }
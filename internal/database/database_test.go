@@ -2,10 +2,14 @@ package database
 
 import (
 	"context"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/database/gen"
 )
 
 func TestTgdb_GetSingleInstanceAudio(t *testing.T) {
@@ -27,3 +31,50 @@ func TestTgdb_GetSingleInstanceAudio(t *testing.T) {
 	assert.Nil(t, got)
 
 }
+
+// TestTgdb_GetOriginMessage needs database with schema of internal/schema/tg.sql in TG_TEST_DSN,
+// changes are rolled back.
+func TestTgdb_GetOriginMessage(t *testing.T) {
+	dsn := os.Getenv("TG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("TG_TEST_DSN is not set")
+	}
+	ctx := context.Background()
+
+	conn, err := pgx.Connect(ctx, dsn)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	tx, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+	d := Tgdb{queries: gen.New(tx)}
+
+	var mediaID, tagID int
+	var topicID uint64
+	var now time.Time
+	require.NoError(t, tx.QueryRow(ctx, `insert into media (title, occurrence_date) values ('Лекция', '2025-01-01') returning id`).Scan(&mediaID))
+	require.NoError(t, tx.QueryRow(ctx, `insert into tag (name) values ('test-origin') returning id`).Scan(&tagID))
+	require.NoError(t, tx.QueryRow(ctx, `insert into tg_topics (message_thread_id, tag_id, name) values (1, $1, 'test-origin') returning id`, tagID).Scan(&topicID))
+	require.NoError(t, tx.QueryRow(ctx, `select now()::timestamp`).Scan(&now))
+
+	addMessage := func(messageID int, created time.Time) {
+		_, err := tx.Exec(ctx, `insert into tg_messages (topic_id, media_id, message_id, document_id, access_hash, file_reference, created)
+			values ($1, $2, $3, 1, 2, '\x03', $4)`, topicID, mediaID, messageID, created)
+		require.NoError(t, err)
+	}
+
+	// post of the previous publishing
+	addMessage(10, now.Add(-24*time.Hour))
+	_, err = d.GetOriginMessage(ctx, mediaID, topicID, now.Add(-time.Hour))
+	require.ErrorIs(t, err, ErrMessageNotFound)
+
+	m, err := d.GetOriginMessage(ctx, mediaID, topicID, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 10, m.MessageID)
+
+	// post of media queued again
+	addMessage(20, now)
+	m, err = d.GetOriginMessage(ctx, mediaID, topicID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 20, m.MessageID)
+}
@@ -88,7 +88,7 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
-// Config for sending messages. For future use when will be many chatbots on one server
+// Config for sending messages. Settings are loaded by slug, see tg start --slug
 type TgConfig struct {
 	ID uint64 `json:"id"`
 	// Unique slug for config. Used for getting config by slug
@@ -97,6 +97,39 @@ type TgConfig struct {
 	RecentUploadTime time.Time `json:"recent_upload_time"`
 	// Bot settings for sending messages.
 	Settings json.RawMessage `json:"settings"`
+	// Publishing of all topics of config is paused, see tg pause
+	Paused bool `json:"paused"`
+}
+
+// Mirrors of topic. Document uploaded to topic is sent to each destination without uploading file again, see tg topics destinations
+type TgDestination struct {
+	ID      uint64 `json:"id"`
+	TopicID int    `json:"topic_id"`
+	Name    string `json:"name"`
+	// MTProto ID of channel or supergroup, without -100 prefix
+	ChannelID int `json:"channel_id"`
+	// Access hash of channel for the bot
+	AccessHash int `json:"access_hash"`
+	// Topic of forum group, null for channel or group without topics
+	MessageThreadID *int `json:"message_thread_id"`
+	// Caption template of destination, null - caption_template of config
+	CaptionTemplate *string   `json:"caption_template"`
+	Created         time.Time `json:"created"`
+}
+
+// Published messages with media. Document is sent again to answer commands of listeners without uploading file.
+type TgMessage struct {
+	ID      uint64 `json:"id"`
+	TopicID int    `json:"topic_id"`
+	MediaID int    `json:"media_id"`
+	// ID of message in group of topic
+	MessageID     int       `json:"message_id"`
+	DocumentID    int       `json:"document_id"`
+	AccessHash    int       `json:"access_hash"`
+	FileReference []byte    `json:"file_reference"`
+	Created       time.Time `json:"created"`
+	// Message forwarded or sent again to topic if media is posted once, see telegram.multi_tag. Null - media was posted
+	OriginID *int `json:"origin_id"`
 }
 
 type TgQueue struct {
@@ -104,6 +137,10 @@ type TgQueue struct {
 	TopicID int    `json:"topic_id"`
 	MediaID int    `json:"media_id"`
 	TagID   int    `json:"tag_id"`
+	// Media with higher priority are published first. Fresh media inserted by trigger have 100, populated archive has 0 by default.
+	Priority int `json:"priority"`
+	// Media is queued, message of media posted before is not forwarded to topic, see telegram.multi_tag
+	Created time.Time `json:"created"`
 }
 
 type TgQueueFailed struct {
@@ -112,6 +149,20 @@ type TgQueueFailed struct {
 	MediaID int    `json:"media_id"`
 	TagID   int    `json:"tag_id"`
 	Error   string `json:"error"`
+	// Media was queued, it is queued with the same time again by tg queue retry-failed
+	Queued *time.Time `json:"queued"`
+}
+
+// Subscriptions of listeners to tags, see bot commands /subscribe and /unsubscribe.
+type TgSubscription struct {
+	ID uint64 `json:"id"`
+	// Config (bot) which sends media to subscriber
+	ConfigID *int `json:"config_id"`
+	UserID   int  `json:"user_id"`
+	// Access hash of user for the bot, it is required to send direct message
+	AccessHash int       `json:"access_hash"`
+	TagID      int       `json:"tag_id"`
+	Created    time.Time `json:"created"`
 }
 
 type TgTopic struct {
@@ -121,4 +172,10 @@ type TgTopic struct {
 	Name              string     `json:"name"`
 	IconCustomEmojiID *string    `json:"icon_custom_emoji_id"`
 	Created           *time.Time `json:"created"`
+	// Delivery policy of topic, overrides global one. Example: {"timezone": "Europe/Moscow", "drip": {"posts_per_hour": 2, "window": "09:00-21:00", "silent": true}, "quiet": {"window": "22:00-08:00", "mode": "silent"}}
+	Delivery json.RawMessage `json:"delivery"`
+	// Config (bot and group) of topic. Queue of topic is published by this config, see tg start --all
+	ConfigID *int `json:"config_id"`
+	// Publishing of topic is paused, see tg pause --topic
+	Paused bool `json:"paused"`
 }
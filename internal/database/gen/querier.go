@@ -10,19 +10,47 @@ import (
 )
 
 type Querier interface {
+	AddDestination(ctx context.Context, arg AddDestinationParams) (uint64, error)
 	AddMediaToFailedQueue(ctx context.Context, arg AddMediaToFailedQueueParams) error
+	AddMediaToQueue(ctx context.Context, arg AddMediaToQueueParams) (int64, error)
+	AddMessage(ctx context.Context, arg AddMessageParams) error
+	AddSubscription(ctx context.Context, arg AddSubscriptionParams) (int64, error)
 	ClearFailedMediaFromQueue(ctx context.Context, mediaID int) error
+	ClearQueue(ctx context.Context, arg ClearQueueParams) (int64, error)
+	DeleteDestination(ctx context.Context, id uint64) (int64, error)
+	DeleteFromQueue(ctx context.Context, arg DeleteFromQueueParams) (int64, error)
+	DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) (int64, error)
+	DeleteUserSubscriptions(ctx context.Context, arg DeleteUserSubscriptionsParams) (int64, error)
 	GetConfig(ctx context.Context, slug string) (TgConfig, error)
 	GetMediaDataTelegram(ctx context.Context, mediaID int) (GetMediaDataTelegramRow, error)
+	GetOriginMessage(ctx context.Context, arg GetOriginMessageParams) (GetOriginMessageRow, error)
+	GetQueueStats(ctx context.Context, configID *int) (GetQueueStatsRow, error)
 	GetRecentUploadTime(ctx context.Context, slug string) (time.Time, error)
+	GetTagByName(ctx context.Context, name string) (Tag, error)
+	IsPaused(ctx context.Context) (bool, error)
+	IsTopicPaused(ctx context.Context, id uint64) (bool, error)
 	LinkMediaToTelegram(ctx context.Context, arg LinkMediaToTelegramParams) error
 	ListAllTopics(ctx context.Context) ([]ListAllTopicsRow, error)
+	ListConfigs(ctx context.Context) ([]TgConfig, error)
+	ListDestinations(ctx context.Context, topicID *int) ([]ListDestinationsRow, error)
+	ListLatestMessages(ctx context.Context, arg ListLatestMessagesParams) ([]ListLatestMessagesRow, error)
+	ListMediaTags(ctx context.Context, arg ListMediaTagsParams) ([]string, error)
 	ListMediaQueue(ctx context.Context, arg ListMediaQueueParams) ([]ListMediaQueueRow, error)
+	ListPopulateCandidates(ctx context.Context, arg ListPopulateCandidatesParams) ([]ListPopulateCandidatesRow, error)
+	ListQueue(ctx context.Context, arg ListQueueParams) ([]ListQueueRow, error)
+	ListSubscribers(ctx context.Context, arg ListSubscribersParams) ([]ListSubscribersRow, error)
+	ListUserSubscriptions(ctx context.Context, arg ListUserSubscriptionsParams) ([]string, error)
 	MakeTopicPublished(ctx context.Context, arg MakeTopicPublishedParams) error
-	PopulateMedia(ctx context.Context, occurrenceDate time.Time) error
-	PopulateMediaWithTagID(ctx context.Context, arg PopulateMediaWithTagIDParams) error
-	RemoveMediaQueue(ctx context.Context, arg RemoveMediaQueueParams) error
+	PopulateQueue(ctx context.Context, arg PopulateQueueParams) (int64, error)
+	PrioritizeQueue(ctx context.Context, arg PrioritizeQueueParams) (int64, error)
+	RetryFailed(ctx context.Context, arg RetryFailedParams) (int64, error)
+	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
+	SetConfigPaused(ctx context.Context, arg SetConfigPausedParams) (int64, error)
+	SetConfigPeer(ctx context.Context, arg SetConfigPeerParams) (int64, error)
+	SetPaused(ctx context.Context, paused bool) error
 	SetRecentUploadTime(ctx context.Context, arg SetRecentUploadTimeParams) error
+	SetTopicDelivery(ctx context.Context, arg SetTopicDeliveryParams) error
+	SetTopicPaused(ctx context.Context, arg SetTopicPausedParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,36 +7,144 @@ package gen
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
+const addDestination = `-- name: AddDestination :one
+insert into tg_destinations (topic_id, name, channel_id, access_hash, message_thread_id, caption_template)
+values ($1, $2, $3, $4, $5, $6)
+returning id
+`
+
+type AddDestinationParams struct {
+	TopicID         int     `json:"topic_id"`
+	Name            string  `json:"name"`
+	ChannelID       int     `json:"channel_id"`
+	AccessHash      int     `json:"access_hash"`
+	MessageThreadID *int    `json:"message_thread_id"`
+	CaptionTemplate *string `json:"caption_template"`
+}
+
+func (q *Queries) AddDestination(ctx context.Context, arg AddDestinationParams) (uint64, error) {
+	row := q.db.QueryRow(ctx, addDestination,
+		arg.TopicID,
+		arg.Name,
+		arg.ChannelID,
+		arg.AccessHash,
+		arg.MessageThreadID,
+		arg.CaptionTemplate,
+	)
+	var id uint64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const addMediaToFailedQueue = `-- name: AddMediaToFailedQueue :exec
-WITH topic_lookup AS (
-    SELECT id as topic_id FROM tg_topics WHERE message_thread_id = $1 LIMIT 1
-)
 insert into tg_queue_failed
-    (topic_id, media_id, tag_id, error)
-select topic_id, $2, $3, $4
-from topic_lookup
+    (topic_id, media_id, tag_id, error, queued)
+values ($1, $2, $3, $4, $5)
 `
 
 type AddMediaToFailedQueueParams struct {
-	MessageThreadID int    `json:"message_thread_id"`
-	MediaID         int    `json:"media_id"`
-	TagID           int    `json:"tag_id"`
-	Error           string `json:"error"`
+	TopicID uint64     `json:"topic_id"`
+	MediaID int        `json:"media_id"`
+	TagID   int        `json:"tag_id"`
+	Error   string     `json:"error"`
+	Queued  *time.Time `json:"queued"`
 }
 
 func (q *Queries) AddMediaToFailedQueue(ctx context.Context, arg AddMediaToFailedQueueParams) error {
 	_, err := q.db.Exec(ctx, addMediaToFailedQueue,
-		arg.MessageThreadID,
+		arg.TopicID,
 		arg.MediaID,
 		arg.TagID,
 		arg.Error,
+		arg.Queued,
+	)
+	return err
+}
+
+const addMediaToQueue = `-- name: AddMediaToQueue :execrows
+insert into tg_queue (topic_id, media_id, tag_id, priority)
+select tt.id, mt.media_id, mt.tag_id, $1
+from media_tag mt
+join tg_topics tt on tt.tag_id = mt.tag_id
+where
+    mt.media_id = $2
+    and ($3::int is null or mt.tag_id = $3)
+on conflict do nothing
+`
+
+type AddMediaToQueueParams struct {
+	Priority int  `json:"priority"`
+	MediaID  int  `json:"media_id"`
+	TagID    *int `json:"tag_id"`
+}
+
+func (q *Queries) AddMediaToQueue(ctx context.Context, arg AddMediaToQueueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addMediaToQueue, arg.Priority, arg.MediaID, arg.TagID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const addMessage = `-- name: AddMessage :exec
+insert into tg_messages
+    (topic_id, media_id, message_id, document_id, access_hash, file_reference, origin_id)
+values ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type AddMessageParams struct {
+	TopicID       int    `json:"topic_id"`
+	MediaID       int    `json:"media_id"`
+	MessageID     int    `json:"message_id"`
+	DocumentID    int    `json:"document_id"`
+	AccessHash    int    `json:"access_hash"`
+	FileReference []byte `json:"file_reference"`
+	OriginID      *int   `json:"origin_id"`
+}
+
+func (q *Queries) AddMessage(ctx context.Context, arg AddMessageParams) error {
+	_, err := q.db.Exec(ctx, addMessage,
+		arg.TopicID,
+		arg.MediaID,
+		arg.MessageID,
+		arg.DocumentID,
+		arg.AccessHash,
+		arg.FileReference,
+		arg.OriginID,
 	)
 	return err
 }
 
+const addSubscription = `-- name: AddSubscription :execrows
+insert into tg_subscriptions (config_id, user_id, access_hash, tag_id)
+values ($1, $2, $3, $4)
+on conflict do nothing
+`
+
+type AddSubscriptionParams struct {
+	ConfigID   *int `json:"config_id"`
+	UserID     int  `json:"user_id"`
+	AccessHash int  `json:"access_hash"`
+	TagID      int  `json:"tag_id"`
+}
+
+func (q *Queries) AddSubscription(ctx context.Context, arg AddSubscriptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addSubscription,
+		arg.ConfigID,
+		arg.UserID,
+		arg.AccessHash,
+		arg.TagID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const clearFailedMediaFromQueue = `-- name: ClearFailedMediaFromQueue :exec
 delete from tg_queue where media_id = $1
 `
@@ -46,12 +154,109 @@ func (q *Queries) ClearFailedMediaFromQueue(ctx context.Context, mediaID int) er
 	return err
 }
 
+const clearQueue = `-- name: ClearQueue :execrows
+delete from tg_queue tq
+using tg_topics tt
+where
+    tt.id = tq.topic_id
+    and ($1::int is null or tq.tag_id = $1)
+    and ($2::bigint is null or tt.config_id = $2)
+`
+
+type ClearQueueParams struct {
+	TagID    *int `json:"tag_id"`
+	ConfigID *int `json:"config_id"`
+}
+
+func (q *Queries) ClearQueue(ctx context.Context, arg ClearQueueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, clearQueue, arg.TagID, arg.ConfigID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDestination = `-- name: DeleteDestination :execrows
+delete from tg_destinations where id = $1
+`
+
+func (q *Queries) DeleteDestination(ctx context.Context, id uint64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDestination, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteFromQueue = `-- name: DeleteFromQueue :execrows
+delete from tg_queue tq
+using tg_topics tt
+where
+    tq.id = $1
+    and tt.id = tq.topic_id
+    and ($2::bigint is null or tt.config_id = $2)
+`
+
+type DeleteFromQueueParams struct {
+	ID       uint64 `json:"id"`
+	ConfigID *int   `json:"config_id"`
+}
+
+func (q *Queries) DeleteFromQueue(ctx context.Context, arg DeleteFromQueueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFromQueue, arg.ID, arg.ConfigID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSubscription = `-- name: DeleteSubscription :execrows
+delete from tg_subscriptions
+where
+    config_id is not distinct from $1::bigint
+    and user_id = $2
+    and tag_id = $3
+`
+
+type DeleteSubscriptionParams struct {
+	ConfigID *int `json:"config_id"`
+	UserID   int  `json:"user_id"`
+	TagID    int  `json:"tag_id"`
+}
+
+func (q *Queries) DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSubscription, arg.ConfigID, arg.UserID, arg.TagID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserSubscriptions = `-- name: DeleteUserSubscriptions :execrows
+delete from tg_subscriptions
+where config_id is not distinct from $1::bigint and user_id = $2
+`
+
+type DeleteUserSubscriptionsParams struct {
+	ConfigID *int `json:"config_id"`
+	UserID   int  `json:"user_id"`
+}
+
+func (q *Queries) DeleteUserSubscriptions(ctx context.Context, arg DeleteUserSubscriptionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserSubscriptions, arg.ConfigID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getConfig = `-- name: GetConfig :one
 select
     tc.id,
     tc.slug,
     tc.recent_upload_time,
-    tc.settings
+    tc.settings,
+    tc.paused
 from tg_config tc
 where tc.slug = $1
 `
@@ -64,6 +269,7 @@ func (q *Queries) GetConfig(ctx context.Context, slug string) (TgConfig, error)
 		&i.Slug,
 		&i.RecentUploadTime,
 		&i.Settings,
+		&i.Paused,
 	)
 	return i, err
 }
@@ -89,6 +295,75 @@ func (q *Queries) GetMediaDataTelegram(ctx context.Context, mediaID int) (GetMed
 	return i, err
 }
 
+const getOriginMessage = `-- name: GetOriginMessage :one
+select tm.id, tm.topic_id, tm.message_id, tm.document_id, tm.access_hash, tm.file_reference
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+where
+    tm.media_id = $1
+    and tm.origin_id is null
+    and tm.created >= $2::timestamp
+    and tt.config_id is not distinct from (select x.config_id from tg_topics x where x.id = $3)
+order by tm.id
+limit 1
+`
+
+type GetOriginMessageParams struct {
+	MediaID int       `json:"media_id"`
+	Queued  time.Time `json:"queued"`
+	TopicID uint64    `json:"topic_id"`
+}
+
+type GetOriginMessageRow struct {
+	ID            uint64 `json:"id"`
+	TopicID       int    `json:"topic_id"`
+	MessageID     int    `json:"message_id"`
+	DocumentID    int    `json:"document_id"`
+	AccessHash    int    `json:"access_hash"`
+	FileReference []byte `json:"file_reference"`
+}
+
+// posted message of media in topics of the same config as topic since media was queued
+func (q *Queries) GetOriginMessage(ctx context.Context, arg GetOriginMessageParams) (GetOriginMessageRow, error) {
+	row := q.db.QueryRow(ctx, getOriginMessage, arg.MediaID, arg.Queued, arg.TopicID)
+	var i GetOriginMessageRow
+	err := row.Scan(
+		&i.ID,
+		&i.TopicID,
+		&i.MessageID,
+		&i.DocumentID,
+		&i.AccessHash,
+		&i.FileReference,
+	)
+	return i, err
+}
+
+const getQueueStats = `-- name: GetQueueStats :one
+select
+    (
+        select count(*) from tg_queue tq
+        join tg_topics tt on tt.id = tq.topic_id
+        where $1::bigint is null or tt.config_id = $1
+    ) as queue,
+    (
+        select count(*) from tg_queue_failed tf
+        join tg_topics tt on tt.id = tf.topic_id
+        where $1::bigint is null or tt.config_id = $1
+    ) as failed
+`
+
+type GetQueueStatsRow struct {
+	Queue  int `json:"queue"`
+	Failed int `json:"failed"`
+}
+
+func (q *Queries) GetQueueStats(ctx context.Context, configID *int) (GetQueueStatsRow, error) {
+	row := q.db.QueryRow(ctx, getQueueStats, configID)
+	var i GetQueueStatsRow
+	err := row.Scan(&i.Queue, &i.Failed)
+	return i, err
+}
+
 const getRecentUploadTime = `-- name: GetRecentUploadTime :one
 select recent_upload_time from tg_config where slug = $1
 `
@@ -100,6 +375,43 @@ func (q *Queries) GetRecentUploadTime(ctx context.Context, slug string) (time.Ti
 	return recent_upload_time, err
 }
 
+const getTagByName = `-- name: GetTagByName :one
+select id, name from tag where name = $1
+`
+
+func (q *Queries) GetTagByName(ctx context.Context, name string) (Tag, error) {
+	row := q.db.QueryRow(ctx, getTagByName, name)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const isPaused = `-- name: IsPaused :one
+select exists (select 1 from tg_pause) as paused
+`
+
+func (q *Queries) IsPaused(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, isPaused)
+	var paused bool
+	err := row.Scan(&paused)
+	return paused, err
+}
+
+const isTopicPaused = `-- name: IsTopicPaused :one
+select exists (
+    select 1 from tg_topics tt
+    left join tg_config tc on tc.id = tt.config_id
+    where tt.id = $1 and (tt.paused or coalesce(tc.paused, false))
+) or exists (select 1 from tg_pause) as paused
+`
+
+func (q *Queries) IsTopicPaused(ctx context.Context, id uint64) (bool, error) {
+	row := q.db.QueryRow(ctx, isTopicPaused, id)
+	var paused bool
+	err := row.Scan(&paused)
+	return paused, err
+}
+
 const linkMediaToTelegram = `-- name: LinkMediaToTelegram :exec
 insert into media_data
     (media_id, data_type, value)
@@ -117,19 +429,22 @@ func (q *Queries) LinkMediaToTelegram(ctx context.Context, arg LinkMediaToTelegr
 }
 
 const listAllTopics = `-- name: ListAllTopics :many
-select tt.id, tt.message_thread_id, tt.tag_id, tt.name, tt.icon_custom_emoji_id, tt.created, t.name as tag
+select tt.id, tt.message_thread_id, tt.tag_id, tt.name, tt.icon_custom_emoji_id, tt.created, tt.delivery, tt.config_id, tt.paused, t.name as tag
 from tg_topics tt
 join tag t on t.id = tt.tag_id
 `
 
 type ListAllTopicsRow struct {
-	ID                uint64     `json:"id"`
-	MessageThreadID   int        `json:"message_thread_id"`
-	TagID             int        `json:"tag_id"`
-	Name              string     `json:"name"`
-	IconCustomEmojiID *string    `json:"icon_custom_emoji_id"`
-	Created           *time.Time `json:"created"`
-	Tag               string     `json:"tag"`
+	ID                uint64          `json:"id"`
+	MessageThreadID   int             `json:"message_thread_id"`
+	TagID             int             `json:"tag_id"`
+	Name              string          `json:"name"`
+	IconCustomEmojiID *string         `json:"icon_custom_emoji_id"`
+	Created           *time.Time      `json:"created"`
+	Delivery          json.RawMessage `json:"delivery"`
+	ConfigID          *int            `json:"config_id"`
+	Paused            bool            `json:"paused"`
+	Tag               string          `json:"tag"`
 }
 
 func (q *Queries) ListAllTopics(ctx context.Context) ([]ListAllTopicsRow, error) {
@@ -148,6 +463,9 @@ func (q *Queries) ListAllTopics(ctx context.Context) ([]ListAllTopicsRow, error)
 			&i.Name,
 			&i.IconCustomEmojiID,
 			&i.Created,
+			&i.Delivery,
+			&i.ConfigID,
+			&i.Paused,
 			&i.Tag,
 		); err != nil {
 			return nil, err
@@ -160,10 +478,208 @@ func (q *Queries) ListAllTopics(ctx context.Context) ([]ListAllTopicsRow, error)
 	return items, nil
 }
 
+const listConfigs = `-- name: ListConfigs :many
+select id, slug, recent_upload_time, settings, paused from tg_config
+order by id
+`
+
+func (q *Queries) ListConfigs(ctx context.Context) ([]TgConfig, error) {
+	rows, err := q.db.Query(ctx, listConfigs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TgConfig{}
+	for rows.Next() {
+		var i TgConfig
+		if err := rows.Scan(
+			&i.ID,
+			&i.Slug,
+			&i.RecentUploadTime,
+			&i.Settings,
+			&i.Paused,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDestinations = `-- name: ListDestinations :many
+select
+    d.id,
+    d.topic_id,
+    tt.name as topic,
+    d.name,
+    d.channel_id,
+    d.access_hash,
+    d.message_thread_id,
+    d.caption_template
+from tg_destinations d
+join tg_topics tt on tt.id = d.topic_id
+where $1::bigint is null or d.topic_id = $1
+order by d.topic_id, d.id
+`
+
+type ListDestinationsRow struct {
+	ID              uint64  `json:"id"`
+	TopicID         int     `json:"topic_id"`
+	Topic           string  `json:"topic"`
+	Name            string  `json:"name"`
+	ChannelID       int     `json:"channel_id"`
+	AccessHash      int     `json:"access_hash"`
+	MessageThreadID *int    `json:"message_thread_id"`
+	CaptionTemplate *string `json:"caption_template"`
+}
+
+func (q *Queries) ListDestinations(ctx context.Context, topicID *int) ([]ListDestinationsRow, error) {
+	rows, err := q.db.Query(ctx, listDestinations, topicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDestinationsRow{}
+	for rows.Next() {
+		var i ListDestinationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TopicID,
+			&i.Topic,
+			&i.Name,
+			&i.ChannelID,
+			&i.AccessHash,
+			&i.MessageThreadID,
+			&i.CaptionTemplate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLatestMessages = `-- name: ListLatestMessages :many
+select
+    tm.media_id,
+    m.title,
+    tt.id as topic_id,
+    tt.message_thread_id,
+    t.name as tag,
+    tm.message_id,
+    tm.document_id,
+    tm.access_hash,
+    tm.file_reference,
+    tm.created
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+join tag t on t.id = tt.tag_id
+join media m on m.id = tm.media_id
+where
+    ($1::bigint is null or tt.config_id = $1)
+    and ($2::text is null or t.name = $2)
+order by tm.created desc, tm.id desc
+limit $3
+`
+
+type ListLatestMessagesParams struct {
+	ConfigID *int    `json:"config_id"`
+	Tag      *string `json:"tag"`
+	Limit    int32   `json:"limit"`
+}
+
+type ListLatestMessagesRow struct {
+	MediaID         int       `json:"media_id"`
+	Title           string    `json:"title"`
+	TopicID         uint64    `json:"topic_id"`
+	MessageThreadID int       `json:"message_thread_id"`
+	Tag             string    `json:"tag"`
+	MessageID       int       `json:"message_id"`
+	DocumentID      int       `json:"document_id"`
+	AccessHash      int       `json:"access_hash"`
+	FileReference   []byte    `json:"file_reference"`
+	Created         time.Time `json:"created"`
+}
+
+func (q *Queries) ListLatestMessages(ctx context.Context, arg ListLatestMessagesParams) ([]ListLatestMessagesRow, error) {
+	rows, err := q.db.Query(ctx, listLatestMessages, arg.ConfigID, arg.Tag, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLatestMessagesRow{}
+	for rows.Next() {
+		var i ListLatestMessagesRow
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Title,
+			&i.TopicID,
+			&i.MessageThreadID,
+			&i.Tag,
+			&i.MessageID,
+			&i.DocumentID,
+			&i.AccessHash,
+			&i.FileReference,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaTags = `-- name: ListMediaTags :many
+select distinct t.name
+from media_tag mt
+join tag t on t.id = mt.tag_id
+join tg_topics tt on tt.tag_id = mt.tag_id
+where
+    mt.media_id = $1
+    and tt.config_id is not distinct from (select x.config_id from tg_topics x where x.id = $2)
+order by t.name
+`
+
+type ListMediaTagsParams struct {
+	MediaID int    `json:"media_id"`
+	TopicID uint64 `json:"topic_id"`
+}
+
+// tags of media which have topics of the same config as topic
+func (q *Queries) ListMediaTags(ctx context.Context, arg ListMediaTagsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listMediaTags, arg.MediaID, arg.TopicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMediaQueue = `-- name: ListMediaQueue :many
 select
-    tq.id cursor,
+    tq.id,
     tq.media_id,
+    tq.topic_id,
     m.title,
     m.teaser,
     m.file_url,
@@ -173,40 +689,51 @@ select
     m.duration,
     m.size,
     t.id as tag_id,
-    t.name as tag
+    t.name as tag,
+    tt.delivery,
+    tq.created as queued
 from tg_queue tq
 join tag t on t.id = tq.tag_id
 join tg_topics tt on tt.id = tq.topic_id
 join media m on m.id = tq.media_id
+left join tg_config tc on tc.id = tt.config_id
 where 
     m.file_url is not null
-    and tq.id > $1 -- $1 is the last id in the previous query = cursor
-order by m.occurrence_date asc
-limit $2
+    and tq.id <> all($1::bigint[]) -- items already sent to processor
+    and ($2::bigint is null or tt.config_id = $2)
+    and not tt.paused
+    and not coalesce(tc.paused, false)
+    and not exists (select 1 from tg_pause)
+order by tq.priority desc, m.occurrence_date asc, tq.id asc
+limit $3
 `
 
 type ListMediaQueueParams struct {
-	ID    uint64 `json:"id"`
-	Limit int32  `json:"limit"`
+	Exclude  []int `json:"exclude"`
+	ConfigID *int  `json:"config_id"`
+	Limit    int32 `json:"limit"`
 }
 
 type ListMediaQueueRow struct {
-	Cursor          uint64         `json:"cursor"`
-	MediaID         int            `json:"media_id"`
-	Title           string         `json:"title"`
-	Teaser          *string        `json:"teaser"`
-	FileUrl         *string        `json:"file_url"`
-	MessageThreadID int            `json:"message_thread_id"`
-	OccurrenceDate  time.Time      `json:"occurrence_date"`
-	IssueDate       *time.Time     `json:"issue_date"`
-	Duration        *time.Duration `json:"duration"`
-	Size            *int           `json:"size"`
-	TagID           int            `json:"tag_id"`
-	Tag             string         `json:"tag"`
+	ID              uint64          `json:"id"`
+	MediaID         int             `json:"media_id"`
+	TopicID         int             `json:"topic_id"`
+	Title           string          `json:"title"`
+	Teaser          *string         `json:"teaser"`
+	FileUrl         *string         `json:"file_url"`
+	MessageThreadID int             `json:"message_thread_id"`
+	OccurrenceDate  time.Time       `json:"occurrence_date"`
+	IssueDate       *time.Time      `json:"issue_date"`
+	Duration        *time.Duration  `json:"duration"`
+	Size            *int            `json:"size"`
+	TagID           int             `json:"tag_id"`
+	Tag             string          `json:"tag"`
+	Delivery        json.RawMessage `json:"delivery"`
+	Queued          time.Time       `json:"queued"`
 }
 
 func (q *Queries) ListMediaQueue(ctx context.Context, arg ListMediaQueueParams) ([]ListMediaQueueRow, error) {
-	rows, err := q.db.Query(ctx, listMediaQueue, arg.ID, arg.Limit)
+	rows, err := q.db.Query(ctx, listMediaQueue, arg.Exclude, arg.ConfigID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -215,8 +742,9 @@ func (q *Queries) ListMediaQueue(ctx context.Context, arg ListMediaQueueParams)
 	for rows.Next() {
 		var i ListMediaQueueRow
 		if err := rows.Scan(
-			&i.Cursor,
+			&i.ID,
 			&i.MediaID,
+			&i.TopicID,
 			&i.Title,
 			&i.Teaser,
 			&i.FileUrl,
@@ -227,6 +755,82 @@ func (q *Queries) ListMediaQueue(ctx context.Context, arg ListMediaQueueParams)
 			&i.Size,
 			&i.TagID,
 			&i.Tag,
+			&i.Delivery,
+			&i.Queued,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPopulateCandidates = `-- name: ListPopulateCandidates :many
+SELECT topic_id, topic, media_id, title, file_url, occurrence_date, tag_id, tag, published
+FROM tg_populate_candidates(
+    $1::bigint,
+    $2::timestamp,
+    $3::timestamp,
+    $4::int,
+    $5::bigint,
+    $6::int[],
+    $7::bool
+)
+ORDER BY occurrence_date ASC, topic_id ASC
+`
+
+type ListPopulateCandidatesParams struct {
+	ConfigID      *int       `json:"config_id"`
+	Since         *time.Time `json:"since"`
+	Until         *time.Time `json:"until"`
+	TagID         *int       `json:"tag_id"`
+	TopicID       *int       `json:"topic_id"`
+	MediaIds      []int      `json:"media_ids"`
+	SkipPublished bool       `json:"skip_published"`
+}
+
+type ListPopulateCandidatesRow struct {
+	TopicID        uint64    `json:"topic_id"`
+	Topic          string    `json:"topic"`
+	MediaID        int       `json:"media_id"`
+	Title          string    `json:"title"`
+	FileUrl        *string   `json:"file_url"`
+	OccurrenceDate time.Time `json:"occurrence_date"`
+	TagID          int       `json:"tag_id"`
+	Tag            string    `json:"tag"`
+	Published      bool      `json:"published"`
+}
+
+func (q *Queries) ListPopulateCandidates(ctx context.Context, arg ListPopulateCandidatesParams) ([]ListPopulateCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, listPopulateCandidates,
+		arg.ConfigID,
+		arg.Since,
+		arg.Until,
+		arg.TagID,
+		arg.TopicID,
+		arg.MediaIds,
+		arg.SkipPublished,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPopulateCandidatesRow{}
+	for rows.Next() {
+		var i ListPopulateCandidatesRow
+		if err := rows.Scan(
+			&i.TopicID,
+			&i.Topic,
+			&i.MediaID,
+			&i.Title,
+			&i.FileUrl,
+			&i.OccurrenceDate,
+			&i.TagID,
+			&i.Tag,
+			&i.Published,
 		); err != nil {
 			return nil, err
 		}
@@ -238,6 +842,140 @@ func (q *Queries) ListMediaQueue(ctx context.Context, arg ListMediaQueueParams)
 	return items, nil
 }
 
+const listQueue = `-- name: ListQueue :many
+select
+    tq.id,
+    tq.media_id,
+    tq.priority,
+    m.title,
+    m.file_url,
+    m.occurrence_date,
+    t.name as tag,
+    tt.name as topic
+from tg_queue tq
+join tag t on t.id = tq.tag_id
+join tg_topics tt on tt.id = tq.topic_id
+join media m on m.id = tq.media_id
+where $1::bigint is null or tt.config_id = $1
+order by tq.priority desc, m.occurrence_date asc, tq.id asc
+limit $2
+`
+
+type ListQueueParams struct {
+	ConfigID *int  `json:"config_id"`
+	Limit    int32 `json:"limit"`
+}
+
+type ListQueueRow struct {
+	ID             uint64    `json:"id"`
+	MediaID        int       `json:"media_id"`
+	Priority       int       `json:"priority"`
+	Title          string    `json:"title"`
+	FileUrl        *string   `json:"file_url"`
+	OccurrenceDate time.Time `json:"occurrence_date"`
+	Tag            string    `json:"tag"`
+	Topic          string    `json:"topic"`
+}
+
+func (q *Queries) ListQueue(ctx context.Context, arg ListQueueParams) ([]ListQueueRow, error) {
+	rows, err := q.db.Query(ctx, listQueue, arg.ConfigID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListQueueRow{}
+	for rows.Next() {
+		var i ListQueueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Priority,
+			&i.Title,
+			&i.FileUrl,
+			&i.OccurrenceDate,
+			&i.Tag,
+			&i.Topic,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscribers = `-- name: ListSubscribers :many
+select user_id, access_hash
+from tg_subscriptions
+where config_id is not distinct from $1::bigint and tag_id = $2
+order by id
+`
+
+type ListSubscribersParams struct {
+	ConfigID *int `json:"config_id"`
+	TagID    int  `json:"tag_id"`
+}
+
+type ListSubscribersRow struct {
+	UserID     int `json:"user_id"`
+	AccessHash int `json:"access_hash"`
+}
+
+func (q *Queries) ListSubscribers(ctx context.Context, arg ListSubscribersParams) ([]ListSubscribersRow, error) {
+	rows, err := q.db.Query(ctx, listSubscribers, arg.ConfigID, arg.TagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSubscribersRow{}
+	for rows.Next() {
+		var i ListSubscribersRow
+		if err := rows.Scan(&i.UserID, &i.AccessHash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserSubscriptions = `-- name: ListUserSubscriptions :many
+select t.name
+from tg_subscriptions s
+join tag t on t.id = s.tag_id
+where s.config_id is not distinct from $1::bigint and s.user_id = $2
+order by t.name
+`
+
+type ListUserSubscriptionsParams struct {
+	ConfigID *int `json:"config_id"`
+	UserID   int  `json:"user_id"`
+}
+
+func (q *Queries) ListUserSubscriptions(ctx context.Context, arg ListUserSubscriptionsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listUserSubscriptions, arg.ConfigID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const makeTopicPublished = `-- name: MakeTopicPublished :exec
 update tg_topics
 set 
@@ -256,63 +994,251 @@ func (q *Queries) MakeTopicPublished(ctx context.Context, arg MakeTopicPublished
 	return err
 }
 
-const populateMedia = `-- name: PopulateMedia :exec
-insert into tg_queue (topic_id, media_id, tag_id)
-SELECT tt.id, m.id, mt.tag_id
-FROM media m 
-JOIN media_tag mt ON m.id = mt.media_id 
-JOIN tag t ON t.id = mt.tag_id 
-JOIN tg_topics tt ON tt.tag_id = mt.tag_id 
-left join tg_queue tq on tq.media_id = m.id
-WHERE
-	m.occurrence_date > $1 
-	AND m.file_url IS NOT NULL
-	AND tq.media_id IS NULL
-ORDER BY m.occurrence_date ASC
+const populateQueue = `-- name: PopulateQueue :execrows
+insert into tg_queue (topic_id, media_id, tag_id, priority)
+SELECT c.topic_id, c.media_id, c.tag_id, $1
+FROM tg_populate_candidates(
+    $2::bigint,
+    $3::timestamp,
+    $4::timestamp,
+    $5::int,
+    $6::bigint,
+    $7::int[],
+    $8::bool
+) c
+ORDER BY c.occurrence_date ASC
+on conflict do nothing
 `
 
-func (q *Queries) PopulateMedia(ctx context.Context, occurrenceDate time.Time) error {
-	_, err := q.db.Exec(ctx, populateMedia, occurrenceDate)
-	return err
+type PopulateQueueParams struct {
+	Priority      int        `json:"priority"`
+	ConfigID      *int       `json:"config_id"`
+	Since         *time.Time `json:"since"`
+	Until         *time.Time `json:"until"`
+	TagID         *int       `json:"tag_id"`
+	TopicID       *int       `json:"topic_id"`
+	MediaIds      []int      `json:"media_ids"`
+	SkipPublished bool       `json:"skip_published"`
 }
 
-const populateMediaWithTagID = `-- name: PopulateMediaWithTagID :exec
-insert into tg_queue (topic_id, media_id, tag_id)
-SELECT tt.id, m.id, mt.tag_id
-FROM media m 
-JOIN media_tag mt ON m.id = mt.media_id 
-JOIN tag t ON t.id = mt.tag_id 
-JOIN tg_topics tt ON tt.tag_id = mt.tag_id 
-left join tg_queue tq on tq.media_id = m.id
-WHERE
-	m.occurrence_date > $1 
-    AND t.id = $2
-	AND m.file_url IS NOT NULL
-	AND tq.media_id IS NULL
-ORDER BY m.occurrence_date ASC
+func (q *Queries) PopulateQueue(ctx context.Context, arg PopulateQueueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, populateQueue,
+		arg.Priority,
+		arg.ConfigID,
+		arg.Since,
+		arg.Until,
+		arg.TagID,
+		arg.TopicID,
+		arg.MediaIds,
+		arg.SkipPublished,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const prioritizeQueue = `-- name: PrioritizeQueue :execrows
+update tg_queue
+set priority = (select coalesce(max(q.priority), 0) + 1 from tg_queue q)
+from tg_topics tt
+where
+    tg_queue.id = $1
+    and tt.id = tg_queue.topic_id
+    and ($2::bigint is null or tt.config_id = $2)
 `
 
-type PopulateMediaWithTagIDParams struct {
-	OccurrenceDate time.Time `json:"occurrence_date"`
-	ID             int       `json:"id"`
+type PrioritizeQueueParams struct {
+	ID       uint64 `json:"id"`
+	ConfigID *int   `json:"config_id"`
 }
 
-func (q *Queries) PopulateMediaWithTagID(ctx context.Context, arg PopulateMediaWithTagIDParams) error {
-	_, err := q.db.Exec(ctx, populateMediaWithTagID, arg.OccurrenceDate, arg.ID)
-	return err
+func (q *Queries) PrioritizeQueue(ctx context.Context, arg PrioritizeQueueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, prioritizeQueue, arg.ID, arg.ConfigID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retryFailed = `-- name: RetryFailed :execrows
+with moved as (
+    delete from tg_queue_failed tf
+    using tg_topics tt
+    where tt.id = tf.topic_id
+        and ($1::bigint is null or tt.config_id = $1)
+    returning tf.topic_id, tf.media_id, tf.tag_id, tf.queued
+)
+insert into tg_queue (topic_id, media_id, tag_id, priority, created)
+select topic_id, media_id, tag_id, $2, coalesce(queued, now()) from moved
+on conflict do nothing
+`
+
+type RetryFailedParams struct {
+	ConfigID *int `json:"config_id"`
+	Priority int  `json:"priority"`
+}
+
+func (q *Queries) RetryFailed(ctx context.Context, arg RetryFailedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, retryFailed, arg.ConfigID, arg.Priority)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const removeMediaQueue = `-- name: RemoveMediaQueue :exec
-delete from tg_queue where media_id = $1 and tag_id = $2
+const searchMessages = `-- name: SearchMessages :many
+select
+    tm.media_id,
+    m.title,
+    tt.id as topic_id,
+    tt.message_thread_id,
+    t.name as tag,
+    tm.message_id,
+    tm.document_id,
+    tm.access_hash,
+    tm.file_reference,
+    tm.created
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+join tag t on t.id = tt.tag_id
+join media m on m.id = tm.media_id
+where
+    ($1::bigint is null or tt.config_id = $1)
+    and (m.title || ' ' || coalesce(m.teaser, '')) ilike all($2::text[])
+    -- the latest message of media published in several topics
+    and tm.id = (
+        select max(x.id)
+        from tg_messages x
+        join tg_topics xt on xt.id = x.topic_id
+        where x.media_id = tm.media_id
+            and ($1::bigint is null or xt.config_id = $1)
+    )
+order by m.occurrence_date desc, tm.id desc
+limit $3 offset $4
 `
 
-type RemoveMediaQueueParams struct {
-	MediaID int `json:"media_id"`
-	TagID   int `json:"tag_id"`
+type SearchMessagesParams struct {
+	ConfigID *int     `json:"config_id"`
+	Patterns []string `json:"patterns"`
+	Limit    int32    `json:"limit"`
+	Offset   int32    `json:"offset"`
+}
+
+type SearchMessagesRow struct {
+	MediaID         int       `json:"media_id"`
+	Title           string    `json:"title"`
+	TopicID         uint64    `json:"topic_id"`
+	MessageThreadID int       `json:"message_thread_id"`
+	Tag             string    `json:"tag"`
+	MessageID       int       `json:"message_id"`
+	DocumentID      int       `json:"document_id"`
+	AccessHash      int       `json:"access_hash"`
+	FileReference   []byte    `json:"file_reference"`
+	Created         time.Time `json:"created"`
 }
 
-func (q *Queries) RemoveMediaQueue(ctx context.Context, arg RemoveMediaQueueParams) error {
-	_, err := q.db.Exec(ctx, removeMediaQueue, arg.MediaID, arg.TagID)
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchMessages,
+		arg.ConfigID,
+		arg.Patterns,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchMessagesRow{}
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Title,
+			&i.TopicID,
+			&i.MessageThreadID,
+			&i.Tag,
+			&i.MessageID,
+			&i.DocumentID,
+			&i.AccessHash,
+			&i.FileReference,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setConfigPaused = `-- name: SetConfigPaused :execrows
+update tg_config
+set paused = $1
+where slug = $2
+`
+
+type SetConfigPausedParams struct {
+	Paused bool   `json:"paused"`
+	Slug   string `json:"slug"`
+}
+
+func (q *Queries) SetConfigPaused(ctx context.Context, arg SetConfigPausedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setConfigPaused, arg.Paused, arg.Slug)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setConfigPeer = `-- name: SetConfigPeer :execrows
+update tg_config
+set settings = settings || jsonb_build_object(
+    'group_id', $1::bigint,
+    'mtproto_group_id', $2::bigint,
+    'access_hash', $3::bigint
+)
+where slug = $4
+`
+
+type SetConfigPeerParams struct {
+	GroupID        int    `json:"group_id"`
+	MtprotoGroupID int    `json:"mtproto_group_id"`
+	AccessHash     int    `json:"access_hash"`
+	Slug           string `json:"slug"`
+}
+
+func (q *Queries) SetConfigPeer(ctx context.Context, arg SetConfigPeerParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setConfigPeer,
+		arg.GroupID,
+		arg.MtprotoGroupID,
+		arg.AccessHash,
+		arg.Slug,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setPaused = `-- name: SetPaused :exec
+with resumed as (
+    update tg_config set paused = false
+    where not $1::bool
+), cleared as (
+    delete from tg_pause
+    where not $1::bool
+)
+insert into tg_pause (id)
+select true where $1::bool
+on conflict do nothing
+`
+
+// resume of all bots resumes each config too, paused topics are not changed
+func (q *Queries) SetPaused(ctx context.Context, paused bool) error {
+	_, err := q.db.Exec(ctx, setPaused, paused)
 	return err
 }
 
@@ -331,3 +1257,38 @@ func (q *Queries) SetRecentUploadTime(ctx context.Context, arg SetRecentUploadTi
 	_, err := q.db.Exec(ctx, setRecentUploadTime, arg.RecentUploadTime, arg.Slug)
 	return err
 }
+
+const setTopicDelivery = `-- name: SetTopicDelivery :exec
+update tg_topics
+set delivery = $1
+where id = $2
+`
+
+type SetTopicDeliveryParams struct {
+	Delivery json.RawMessage `json:"delivery"`
+	ID       uint64          `json:"id"`
+}
+
+func (q *Queries) SetTopicDelivery(ctx context.Context, arg SetTopicDeliveryParams) error {
+	_, err := q.db.Exec(ctx, setTopicDelivery, arg.Delivery, arg.ID)
+	return err
+}
+
+const setTopicPaused = `-- name: SetTopicPaused :execrows
+update tg_topics
+set paused = $1
+where id = $2
+`
+
+type SetTopicPausedParams struct {
+	Paused bool   `json:"paused"`
+	ID     uint64 `json:"id"`
+}
+
+func (q *Queries) SetTopicPaused(ctx context.Context, arg SetTopicPausedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setTopicPaused, arg.Paused, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,12 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLikePatterns(t *testing.T) {
+	require.Equal(t, []string{"%Gita%", `%100\%%`, `%a\_b%`}, likePatterns([]string{"Gita", "100%", "a_b"}))
+	require.Empty(t, likePatterns(nil))
+}
@@ -13,8 +13,9 @@ select recent_upload_time from tg_config where slug = $1;
 
 -- name: ListMediaQueue :many
 select
-    tq.id cursor,
+    tq.id,
     tq.media_id,
+    tq.topic_id,
     m.title,
     m.teaser,
     m.file_url,
@@ -24,28 +25,109 @@ select
     m.duration,
     m.size,
     t.id as tag_id,
-    t.name as tag
+    t.name as tag,
+    tt.delivery,
+    tq.created as queued
 from tg_queue tq
 join tag t on t.id = tq.tag_id
 join tg_topics tt on tt.id = tq.topic_id
 join media m on m.id = tq.media_id
+left join tg_config tc on tc.id = tt.config_id
 where 
     m.file_url is not null
-    and tq.id > $1 -- $1 is the last id in the previous query = cursor
-order by m.occurrence_date asc
-limit $2;
+    and tq.id <> all(sqlc.arg('exclude')::bigint[]) -- items already sent to processor
+    and (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'))
+    and not tt.paused
+    and not coalesce(tc.paused, false)
+    and not exists (select 1 from tg_pause)
+order by tq.priority desc, m.occurrence_date asc, tq.id asc
+limit sqlc.arg('limit');
 
--- name: RemoveMediaQueue :exec
-delete from tg_queue where media_id = $1 and tag_id = $2;
+-- name: GetQueueStats :one
+select
+    (
+        select count(*) from tg_queue tq
+        join tg_topics tt on tt.id = tq.topic_id
+        where sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id')
+    ) as queue,
+    (
+        select count(*) from tg_queue_failed tf
+        join tg_topics tt on tt.id = tf.topic_id
+        where sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id')
+    ) as failed;
+
+-- name: ListQueue :many
+select
+    tq.id,
+    tq.media_id,
+    tq.priority,
+    m.title,
+    m.file_url,
+    m.occurrence_date,
+    t.name as tag,
+    tt.name as topic
+from tg_queue tq
+join tag t on t.id = tq.tag_id
+join tg_topics tt on tt.id = tq.topic_id
+join media m on m.id = tq.media_id
+where sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id')
+order by tq.priority desc, m.occurrence_date asc, tq.id asc
+limit sqlc.arg('limit');
+
+-- name: AddMediaToQueue :execrows
+insert into tg_queue (topic_id, media_id, tag_id, priority)
+select tt.id, mt.media_id, mt.tag_id, sqlc.arg('priority')
+from media_tag mt
+join tg_topics tt on tt.tag_id = mt.tag_id
+where
+    mt.media_id = sqlc.arg('media_id')
+    and (sqlc.narg('tag_id')::int is null or mt.tag_id = sqlc.narg('tag_id'))
+on conflict do nothing;
+
+-- name: DeleteFromQueue :execrows
+delete from tg_queue tq
+using tg_topics tt
+where
+    tq.id = sqlc.arg('id')
+    and tt.id = tq.topic_id
+    and (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'));
+
+-- name: ClearQueue :execrows
+delete from tg_queue tq
+using tg_topics tt
+where
+    tt.id = tq.topic_id
+    and (sqlc.narg('tag_id')::int is null or tq.tag_id = sqlc.narg('tag_id'))
+    and (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'));
+
+-- name: PrioritizeQueue :execrows
+update tg_queue
+set priority = (select coalesce(max(q.priority), 0) + 1 from tg_queue q)
+from tg_topics tt
+where
+    tg_queue.id = sqlc.arg('id')
+    and tt.id = tg_queue.topic_id
+    and (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'));
+
+-- name: GetTagByName :one
+select id, name from tag where name = $1;
 
 -- name: AddMediaToFailedQueue :exec
-WITH topic_lookup AS (
-    SELECT id as topic_id FROM tg_topics WHERE message_thread_id = $1 LIMIT 1
-)
 insert into tg_queue_failed
-    (topic_id, media_id, tag_id, error)
-select topic_id, $2, $3, $4
-from topic_lookup;
+    (topic_id, media_id, tag_id, error, queued)
+values ($1, $2, $3, $4, $5);
+
+-- name: RetryFailed :execrows
+with moved as (
+    delete from tg_queue_failed tf
+    using tg_topics tt
+    where tt.id = tf.topic_id
+        and (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'))
+    returning tf.topic_id, tf.media_id, tf.tag_id, tf.queued
+)
+insert into tg_queue (topic_id, media_id, tag_id, priority, created)
+select topic_id, media_id, tag_id, sqlc.arg('priority'), coalesce(queued, now()) from moved
+on conflict do nothing;
 
 -- name: ClearFailedMediaFromQueue :exec
 delete from tg_queue where media_id = $1;
@@ -57,12 +139,64 @@ set
     created = now()
 where id = $2;
 
+-- name: SetTopicDelivery :exec
+update tg_topics
+set delivery = $1
+where id = $2;
+
+-- name: SetConfigPaused :execrows
+update tg_config
+set paused = $1
+where slug = $2;
+
+-- name: SetPaused :exec
+-- resume of all bots resumes each config too, paused topics are not changed
+with resumed as (
+    update tg_config set paused = false
+    where not sqlc.arg('paused')::bool
+), cleared as (
+    delete from tg_pause
+    where not sqlc.arg('paused')::bool
+)
+insert into tg_pause (id)
+select true where sqlc.arg('paused')::bool
+on conflict do nothing;
+
+-- name: IsPaused :one
+select exists (select 1 from tg_pause) as paused;
+
+-- name: SetConfigPeer :execrows
+update tg_config
+set settings = settings || jsonb_build_object(
+    'group_id', sqlc.arg('group_id')::bigint,
+    'mtproto_group_id', sqlc.arg('mtproto_group_id')::bigint,
+    'access_hash', sqlc.arg('access_hash')::bigint
+)
+where slug = sqlc.arg('slug');
+
+-- name: SetTopicPaused :execrows
+update tg_topics
+set paused = $1
+where id = $2;
+
+-- name: IsTopicPaused :one
+select exists (
+    select 1 from tg_topics tt
+    left join tg_config tc on tc.id = tt.config_id
+    where tt.id = $1 and (tt.paused or coalesce(tc.paused, false))
+) or exists (select 1 from tg_pause) as paused;
+
+-- name: ListConfigs :many
+select * from tg_config
+order by id;
+
 -- name: GetConfig :one
 select
     tc.id,
     tc.slug,
     tc.recent_upload_time,
-    tc.settings
+    tc.settings,
+    tc.paused
 from tg_config tc
 where tc.slug = $1;
 
@@ -72,34 +206,33 @@ insert into media_data
 values ($1, 'telegram'::media_data_type, $2);
 
 
--- name: PopulateMedia :exec
-insert into tg_queue (topic_id, media_id, tag_id)
-SELECT tt.id, m.id, mt.tag_id
-FROM media m 
-JOIN media_tag mt ON m.id = mt.media_id 
-JOIN tag t ON t.id = mt.tag_id 
-JOIN tg_topics tt ON tt.tag_id = mt.tag_id 
-left join tg_queue tq on tq.media_id = m.id
-WHERE
-	m.occurrence_date > $1 
-	AND m.file_url IS NOT NULL
-	AND tq.media_id IS NULL
-ORDER BY m.occurrence_date ASC;
-
--- name: PopulateMediaWithTagID :exec
-insert into tg_queue (topic_id, media_id, tag_id)
-SELECT tt.id, m.id, mt.tag_id
-FROM media m 
-JOIN media_tag mt ON m.id = mt.media_id 
-JOIN tag t ON t.id = mt.tag_id 
-JOIN tg_topics tt ON tt.tag_id = mt.tag_id 
-left join tg_queue tq on tq.media_id = m.id
-WHERE
-	m.occurrence_date > $1 
-    AND t.id = $2
-	AND m.file_url IS NOT NULL
-	AND tq.media_id IS NULL
-ORDER BY m.occurrence_date ASC;
+-- name: ListPopulateCandidates :many
+SELECT topic_id, topic, media_id, title, file_url, occurrence_date, tag_id, tag, published
+FROM tg_populate_candidates(
+    sqlc.narg('config_id')::bigint,
+    sqlc.narg('since')::timestamp,
+    sqlc.narg('until')::timestamp,
+    sqlc.narg('tag_id')::int,
+    sqlc.narg('topic_id')::bigint,
+    sqlc.arg('media_ids')::int[],
+    sqlc.arg('skip_published')::bool
+)
+ORDER BY occurrence_date ASC, topic_id ASC;
+
+-- name: PopulateQueue :execrows
+insert into tg_queue (topic_id, media_id, tag_id, priority)
+SELECT c.topic_id, c.media_id, c.tag_id, sqlc.arg('priority')
+FROM tg_populate_candidates(
+    sqlc.narg('config_id')::bigint,
+    sqlc.narg('since')::timestamp,
+    sqlc.narg('until')::timestamp,
+    sqlc.narg('tag_id')::int,
+    sqlc.narg('topic_id')::bigint,
+    sqlc.arg('media_ids')::int[],
+    sqlc.arg('skip_published')::bool
+) c
+ORDER BY c.occurrence_date ASC
+on conflict do nothing;
 
 -- name: GetMediaDataTelegram :one
 SELECT md.media_id, md.value
@@ -108,3 +241,136 @@ WHERE
 	md.media_id = $1
 	AND md.data_type = 'telegram'::media_data_type
 limit 1;
+
+-- name: AddMessage :exec
+insert into tg_messages
+    (topic_id, media_id, message_id, document_id, access_hash, file_reference, origin_id)
+values ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetOriginMessage :one
+-- posted message of media in topics of the same config as topic since media was queued
+select tm.id, tm.topic_id, tm.message_id, tm.document_id, tm.access_hash, tm.file_reference
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+where
+    tm.media_id = sqlc.arg('media_id')
+    and tm.origin_id is null
+    and tm.created >= sqlc.arg('queued')::timestamp
+    and tt.config_id is not distinct from (select x.config_id from tg_topics x where x.id = sqlc.arg('topic_id'))
+order by tm.id
+limit 1;
+
+-- name: ListMediaTags :many
+-- tags of media which have topics of the same config as topic
+select distinct t.name
+from media_tag mt
+join tag t on t.id = mt.tag_id
+join tg_topics tt on tt.tag_id = mt.tag_id
+where
+    mt.media_id = sqlc.arg('media_id')
+    and tt.config_id is not distinct from (select x.config_id from tg_topics x where x.id = sqlc.arg('topic_id'))
+order by t.name;
+
+-- name: AddSubscription :execrows
+insert into tg_subscriptions (config_id, user_id, access_hash, tag_id)
+values (sqlc.narg('config_id'), sqlc.arg('user_id'), sqlc.arg('access_hash'), sqlc.arg('tag_id'))
+on conflict do nothing;
+
+-- name: DeleteSubscription :execrows
+delete from tg_subscriptions
+where
+    config_id is not distinct from sqlc.narg('config_id')::bigint
+    and user_id = sqlc.arg('user_id')
+    and tag_id = sqlc.arg('tag_id');
+
+-- name: DeleteUserSubscriptions :execrows
+delete from tg_subscriptions
+where config_id is not distinct from sqlc.narg('config_id')::bigint and user_id = sqlc.arg('user_id');
+
+-- name: ListUserSubscriptions :many
+select t.name
+from tg_subscriptions s
+join tag t on t.id = s.tag_id
+where s.config_id is not distinct from sqlc.narg('config_id')::bigint and s.user_id = sqlc.arg('user_id')
+order by t.name;
+
+-- name: ListSubscribers :many
+select user_id, access_hash
+from tg_subscriptions
+where config_id is not distinct from sqlc.narg('config_id')::bigint and tag_id = sqlc.arg('tag_id')
+order by id;
+
+-- name: AddDestination :one
+insert into tg_destinations (topic_id, name, channel_id, access_hash, message_thread_id, caption_template)
+values (sqlc.arg('topic_id'), sqlc.arg('name'), sqlc.arg('channel_id'), sqlc.arg('access_hash'), sqlc.narg('message_thread_id'), sqlc.narg('caption_template'))
+returning id;
+
+-- name: DeleteDestination :execrows
+delete from tg_destinations where id = $1;
+
+-- name: ListDestinations :many
+select
+    d.id,
+    d.topic_id,
+    tt.name as topic,
+    d.name,
+    d.channel_id,
+    d.access_hash,
+    d.message_thread_id,
+    d.caption_template
+from tg_destinations d
+join tg_topics tt on tt.id = d.topic_id
+where sqlc.narg('topic_id')::bigint is null or d.topic_id = sqlc.narg('topic_id')
+order by d.topic_id, d.id;
+
+-- name: ListLatestMessages :many
+select
+    tm.media_id,
+    m.title,
+    tt.id as topic_id,
+    tt.message_thread_id,
+    t.name as tag,
+    tm.message_id,
+    tm.document_id,
+    tm.access_hash,
+    tm.file_reference,
+    tm.created
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+join tag t on t.id = tt.tag_id
+join media m on m.id = tm.media_id
+where
+    (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'))
+    and (sqlc.narg('tag')::text is null or t.name = sqlc.narg('tag'))
+order by tm.created desc, tm.id desc
+limit sqlc.arg('limit');
+
+-- name: SearchMessages :many
+select
+    tm.media_id,
+    m.title,
+    tt.id as topic_id,
+    tt.message_thread_id,
+    t.name as tag,
+    tm.message_id,
+    tm.document_id,
+    tm.access_hash,
+    tm.file_reference,
+    tm.created
+from tg_messages tm
+join tg_topics tt on tt.id = tm.topic_id
+join tag t on t.id = tt.tag_id
+join media m on m.id = tm.media_id
+where
+    (sqlc.narg('config_id')::bigint is null or tt.config_id = sqlc.narg('config_id'))
+    and (m.title || ' ' || coalesce(m.teaser, '')) ilike all(sqlc.arg('patterns')::text[])
+    -- the latest message of media published in several topics
+    and tm.id = (
+        select max(x.id)
+        from tg_messages x
+        join tg_topics xt on xt.id = x.topic_id
+        where x.media_id = tm.media_id
+            and (sqlc.narg('config_id')::bigint is null or xt.config_id = sqlc.narg('config_id'))
+    )
+order by m.occurrence_date desc, tm.id desc
+limit sqlc.arg('limit') offset sqlc.arg('offset');
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"gitlab.com/bvgm/tg/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("gitlab.com/bvgm/tg/internal/database")
+
+// queryTracer is pgx tracer that creates span for every query,
+// span is named by sqlc query name, e.g. "db ListMediaQueue".
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "db "+queryName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBQueryText(data.SQL),
+		))
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	// no rows is expected result of many queries, e.g. empty queue
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		tracing.Fail(span, data.Err)
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// queryName returns name of sqlc query from its "-- name: Name :kind" comment.
+func queryName(sql string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(sql, prefix) {
+		return "query"
+	}
+	name, _, _ := strings.Cut(sql[len(prefix):], " ")
+	return name
+}
@@ -0,0 +1,12 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "ListMediaQueue", queryName("-- name: ListMediaQueue :many\nselect 1"))
+	require.Equal(t, "query", queryName("select 1"))
+}
@@ -0,0 +1,149 @@
+package delivery
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// Decision is a verdict of scheduler about queued media.
+type Decision struct {
+	Defer  bool   // media must stay in queue until next time
+	Silent bool   // send message without notification
+	Reason string // why media is deferred
+}
+
+// Scheduler applies delivery policy to media before publishing.
+// Global policy is overridden by topic policy of the media.
+type Scheduler struct {
+	mu     sync.Mutex
+	global domain.DeliveryPolicy
+	sent   map[uint64][]time.Time // tg_topics.id -> publish times during the last hour
+}
+
+func New(global domain.DeliveryPolicy) (*Scheduler, error) {
+	if err := Validate(global); err != nil {
+		return nil, fmt.Errorf("global delivery policy: %w", err)
+	}
+
+	return &Scheduler{
+		global: global,
+		sent:   make(map[uint64][]time.Time),
+	}, nil
+}
+
+// SetGlobal replaces global delivery policy, e.g. on config reload.
+// History of published media is kept, so drip limits stay in effect.
+func (s *Scheduler) SetGlobal(global domain.DeliveryPolicy) error {
+	if err := Validate(global); err != nil {
+		return fmt.Errorf("global delivery policy: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.global = global
+	return nil
+}
+
+// Validate checks that time zone and windows of policy can be parsed.
+func Validate(p domain.DeliveryPolicy) error {
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("load time zone: %w", err)
+		}
+	}
+
+	if p.Drip != nil {
+		if p.Drip.PostsPerHour < 0 {
+			return fmt.Errorf("drip posts per hour must not be negative")
+		}
+		if p.Drip.Window != "" {
+			if _, err := ParseWindow(p.Drip.Window); err != nil {
+				return fmt.Errorf("drip: %w", err)
+			}
+		}
+	}
+
+	if p.Quiet != nil {
+		if p.Quiet.Window != "" {
+			if _, err := ParseWindow(p.Quiet.Window); err != nil {
+				return fmt.Errorf("quiet hours: %w", err)
+			}
+		}
+		switch p.Quiet.Mode {
+		case "", domain.QuietModeSilent, domain.QuietModeDefer:
+		default:
+			return fmt.Errorf("quiet hours: unknown mode %q, expected %q or %q", p.Quiet.Mode, domain.QuietModeSilent, domain.QuietModeDefer)
+		}
+	}
+
+	return nil
+}
+
+// Decide tells if media can be published now.
+func (s *Scheduler) Decide(a domain.Audio, now time.Time) Decision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.global.Override(a.Delivery)
+	now = now.In(location(p.Timezone))
+
+	var d Decision
+	if p.Drip != nil {
+		if p.Drip.Window != "" {
+			// invalid topic window is ignored, it's validated on saving
+			if w, err := ParseWindow(p.Drip.Window); err == nil && !w.Contains(now) {
+				return Decision{Defer: true, Reason: fmt.Sprintf("outside of drip window %s", w)}
+			}
+		}
+
+		if p.Drip.PostsPerHour > 0 && len(s.recent(a.TopicID, now)) >= p.Drip.PostsPerHour {
+			return Decision{Defer: true, Reason: fmt.Sprintf("drip limit of %d posts per hour reached", p.Drip.PostsPerHour)}
+		}
+
+		d.Silent = p.Drip.Silent
+	}
+
+	if p.Quiet != nil && p.Quiet.Window != "" {
+		if w, err := ParseWindow(p.Quiet.Window); err == nil && w.Contains(now) {
+			if p.Quiet.Mode == domain.QuietModeDefer {
+				return Decision{Defer: true, Reason: fmt.Sprintf("quiet hours %s", w)}
+			}
+			d.Silent = true
+		}
+	}
+
+	return d
+}
+
+// Published registers publishing of media to account it in drip limits.
+func (s *Scheduler) Published(a domain.Audio, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent[a.TopicID] = append(s.recent(a.TopicID, now), now)
+}
+
+// recent returns publish times of topic during the last hour. Must be called under lock.
+func (s *Scheduler) recent(topicID uint64, now time.Time) []time.Time {
+	times := s.sent[topicID]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= time.Hour {
+		i++
+	}
+	s.sent[topicID] = times[i:]
+	return s.sent[topicID]
+}
+
+func location(tz string) *time.Location {
+	if tz == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,156 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		at      time.Time
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:  "Inside Day Window",
+			input: "09:00-21:00",
+			at:    time.Date(2025, time.August, 24, 12, 0, 0, 0, time.UTC),
+			want:  true,
+		},
+		{
+			name:  "Outside Day Window",
+			input: "09:00-21:00",
+			at:    time.Date(2025, time.August, 24, 21, 0, 0, 0, time.UTC),
+			want:  false,
+		},
+		{
+			name:  "Inside Window Wrapping Midnight",
+			input: "22:00-07:00",
+			at:    time.Date(2025, time.August, 24, 3, 30, 0, 0, time.UTC),
+			want:  true,
+		},
+		{
+			name:  "Outside Window Wrapping Midnight",
+			input: "22:00-07:00",
+			at:    time.Date(2025, time.August, 24, 7, 0, 0, 0, time.UTC),
+			want:  false,
+		},
+		{
+			name:    "Invalid Format",
+			input:   "9-21",
+			wantErr: true,
+		},
+		{
+			name:    "Empty Window",
+			input:   "10:00-10:00",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWindow(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, w.Contains(tt.at))
+		})
+	}
+}
+
+func TestScheduler_Drip(t *testing.T) {
+	s, err := New(domain.DeliveryPolicy{
+		Timezone: "UTC",
+		Drip:     &domain.DripPolicy{PostsPerHour: 2, Window: "09:00-21:00", Silent: true},
+	})
+	require.NoError(t, err)
+
+	a := domain.Audio{TopicID: 1}
+	now := time.Date(2025, time.August, 24, 10, 0, 0, 0, time.UTC)
+
+	d := s.Decide(a, now)
+	require.False(t, d.Defer)
+	require.True(t, d.Silent)
+
+	s.Published(a, now)
+	s.Published(a, now.Add(time.Minute))
+	require.True(t, s.Decide(a, now.Add(2*time.Minute)).Defer)
+
+	// other topic has its own limit
+	require.False(t, s.Decide(domain.Audio{TopicID: 2}, now).Defer)
+
+	// limit is released in an hour
+	require.False(t, s.Decide(a, now.Add(time.Hour)).Defer)
+
+	// outside of window
+	require.True(t, s.Decide(a, time.Date(2025, time.August, 24, 22, 0, 0, 0, time.UTC)).Defer)
+
+	// topic overrides global policy
+	a.Delivery = domain.DeliveryPolicy{Drip: &domain.DripPolicy{}}
+	d = s.Decide(a, time.Date(2025, time.August, 24, 22, 0, 0, 0, time.UTC))
+	require.False(t, d.Defer)
+	require.False(t, d.Silent)
+}
+
+func TestScheduler_SetGlobal(t *testing.T) {
+	s, err := New(domain.DeliveryPolicy{Timezone: "UTC"})
+	require.NoError(t, err)
+
+	a := domain.Audio{TopicID: 1}
+	now := time.Date(2025, time.August, 24, 10, 0, 0, 0, time.UTC)
+	s.Published(a, now)
+	require.False(t, s.Decide(a, now).Defer)
+
+	// published media are counted by new policy
+	require.NoError(t, s.SetGlobal(domain.DeliveryPolicy{
+		Timezone: "UTC",
+		Drip:     &domain.DripPolicy{PostsPerHour: 1},
+	}))
+	require.True(t, s.Decide(a, now.Add(time.Minute)).Defer)
+
+	require.Error(t, s.SetGlobal(domain.DeliveryPolicy{Timezone: "Mars/Olympus"}))
+	require.True(t, s.Decide(a, now.Add(time.Minute)).Defer)
+}
+
+func TestScheduler_QuietHours(t *testing.T) {
+	s, err := New(domain.DeliveryPolicy{
+		Timezone: "Europe/Moscow",
+		Quiet:    &domain.QuietHours{Window: "22:00-08:00"},
+	})
+	require.NoError(t, err)
+
+	a := domain.Audio{TopicID: 1}
+	night := time.Date(2025, time.August, 24, 20, 0, 0, 0, time.UTC) // 23:00 in Moscow
+	day := time.Date(2025, time.August, 24, 9, 0, 0, 0, time.UTC)    // 12:00 in Moscow
+
+	d := s.Decide(a, night)
+	require.False(t, d.Defer)
+	require.True(t, d.Silent)
+
+	d = s.Decide(a, day)
+	require.False(t, d.Defer)
+	require.False(t, d.Silent)
+
+	// topic defers messages in its own time zone
+	a.Delivery = domain.DeliveryPolicy{
+		Timezone: "Asia/Novosibirsk",
+		Quiet:    &domain.QuietHours{Window: "22:00-08:00", Mode: domain.QuietModeDefer},
+	}
+	require.True(t, s.Decide(a, time.Date(2025, time.August, 24, 16, 0, 0, 0, time.UTC)).Defer) // 23:00 in Novosibirsk
+	require.False(t, s.Decide(a, day).Defer)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(domain.DeliveryPolicy{}))
+	require.Error(t, Validate(domain.DeliveryPolicy{Timezone: "Mars/Olympus"}))
+	require.Error(t, Validate(domain.DeliveryPolicy{Drip: &domain.DripPolicy{Window: "always"}}))
+	require.Error(t, Validate(domain.DeliveryPolicy{Drip: &domain.DripPolicy{PostsPerHour: -1}}))
+	require.Error(t, Validate(domain.DeliveryPolicy{Quiet: &domain.QuietHours{Window: "22:00-08:00", Mode: "mute"}}))
+}
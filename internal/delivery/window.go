@@ -0,0 +1,61 @@
+package delivery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time interval. It may wrap midnight, e.g. "22:00-07:00".
+type Window struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration // offset from midnight
+}
+
+// ParseWindow parses window in format "HH:MM-HH:MM".
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("window %q: expected format HH:MM-HH:MM", s)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q start: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q end: %w", s, err)
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("window %q is empty", s)
+	}
+
+	return Window{Start: start, End: end}, nil
+}
+
+// Contains reports whether t is inside the window. Time is taken in location of t.
+func (w Window) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	// window wraps midnight
+	return offset >= w.Start || offset < w.End
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%s-%s", formatClock(w.Start), formatClock(w.End))
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("parse time of day: %w", err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -4,11 +4,16 @@ import "time"
 
 const DefaultConfigSlug = "goswami.ru"
 
+const (
+	AuthBot  = "bot"  // MTProto session is authorized by bot token, default
+	AuthUser = "user" // MTProto session of user account saved by tg login
+)
+
 type BotSettings struct {
 	BotToken       string `json:"bot_token"`        // Telegram bot token
 	GroupID        int    `json:"group_id"`         // Chat ID of telegram bot
 	MtprotoGroupID int    `json:"mtproto_group_id"` // ChatID without trailing -100
-	AccessHash     int    `json:"access_hash"`      // Group access hash. See GetChannelInfo
+	AccessHash     int    `json:"access_hash"`      // Group access hash. See tg resolve
 	MediaPath      string `json:"audio"`            // Path to directory with audio
 	AssetsPath     string `json:"assets"`           // Path to directory with covers
 	AppID          int    `json:"app_id"`           // telegram application ID. https://my.telegram.org/apps
@@ -22,4 +27,5 @@ type Config struct {
 	Slug             string    // unique name of config
 	RecentUploadTime time.Time // Time of uploading recent audio
 	Settings         BotSettings
+	Paused           bool // publishing of all topics is paused
 }
@@ -0,0 +1,42 @@
+package domain
+
+// DripPolicy limits the pace of publishing in a topic.
+// It's used to backfill archives during weeks without flooding subscribers.
+type DripPolicy struct {
+	PostsPerHour int    `json:"posts_per_hour,omitempty" mapstructure:"posts_per_hour" yaml:"posts_per_hour,omitempty"` // max number of posts per hour in a topic, 0 - unlimited
+	Window       string `json:"window,omitempty" mapstructure:"window" yaml:"window,omitempty"`                         // daily time window to publish, e.g. "09:00-21:00". Empty - all day
+	Silent       bool   `json:"silent,omitempty" mapstructure:"silent" yaml:"silent,omitempty"`                         // send messages without notification
+}
+
+const (
+	QuietModeSilent = "silent" // send without notification during quiet hours
+	QuietModeDefer  = "defer"  // keep media in queue until quiet hours are over
+)
+
+// QuietHours is a daily time window when subscribers must not be disturbed.
+type QuietHours struct {
+	Window string `json:"window,omitempty" mapstructure:"window" yaml:"window,omitempty"` // e.g. "22:00-08:00"
+	Mode   string `json:"mode,omitempty" mapstructure:"mode" yaml:"mode,omitempty"`       // QuietModeSilent (default) or QuietModeDefer
+}
+
+// DeliveryPolicy describes when and how media are delivered to a topic.
+// Global policy is set in config file, topic policy is stored in tg_topics.delivery and overrides global one.
+type DeliveryPolicy struct {
+	Timezone string      `json:"timezone,omitempty" mapstructure:"timezone" yaml:"timezone,omitempty"` // IANA time zone of time windows, e.g. "Europe/Moscow". Empty - local time
+	Drip     *DripPolicy `json:"drip,omitempty" mapstructure:"drip" yaml:"drip,omitempty"`
+	Quiet    *QuietHours `json:"quiet,omitempty" mapstructure:"quiet" yaml:"quiet,omitempty"`
+}
+
+// Override returns policy with topic specific settings applied.
+func (p DeliveryPolicy) Override(o DeliveryPolicy) DeliveryPolicy {
+	if o.Timezone != "" {
+		p.Timezone = o.Timezone
+	}
+	if o.Drip != nil {
+		p.Drip = o.Drip
+	}
+	if o.Quiet != nil {
+		p.Quiet = o.Quiet
+	}
+	return p
+}
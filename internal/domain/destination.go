@@ -0,0 +1,14 @@
+package domain
+
+// Destination is additional chat where media of topic is published: broadcast channel,
+// group or topic of another forum group. Document uploaded to topic is sent again, see tg_destinations.
+type Destination struct {
+	ID              uint64
+	TopicID         uint64 // tg_topics.id
+	Topic           string // name of topic
+	Name            string
+	ChannelID       int64 // MTProto ID of channel or supergroup
+	AccessHash      int64
+	MessageThreadID int    // topic of forum group, 0 - channel or group without topics
+	CaptionTemplate string // empty - caption template of config
+}
@@ -10,18 +10,25 @@ import (
 )
 
 type Audio struct {
-	MediaID         int // media.id
+	QueueID         uint64    // tg_queue.id
+	Queued          time.Time // tg_queue.created
+	MediaID         int       // media.id
 	Title           string
 	Teaser          *string
 	Path            string
 	MessageThreadID int
-	TagID           int // tag.id
+	TopicID         uint64 // tg_topics.id
+	TagID           int    // tag.id
 	Tag             string
+	Tags            []string // names of all tags of media with topics, loaded if media is posted once, see MultiTagForward
 	OccurrenceDate  time.Time
 	IssueDate       *time.Time
 	Performer       string
 	Duration        *time.Duration
 	Size            *int
+	Delivery        DeliveryPolicy // topic delivery policy, overrides global one
+	Silent          bool           // send without notification
+	Caption         string         // rendered caption, empty - title and hashtag
 }
 
 func (a Audio) FullLocalPath(basePath string) Audio {
@@ -37,6 +44,16 @@ func (a Audio) SetPerformer(p string) Audio {
 	return a
 }
 
+func (a Audio) SetSilent(s bool) Audio {
+	a.Silent = s
+	return a
+}
+
+func (a Audio) SetCaption(c string) Audio {
+	a.Caption = c
+	return a
+}
+
 func (a Audio) Exist() (bool, error) {
 	info, err := os.Stat(a.Path)
 	if err == nil {
@@ -52,3 +69,16 @@ func (a Audio) HashTag() string {
 	r := strings.NewReplacer("-", "_", " ", "_")
 	return fmt.Sprintf("#%s", r.Replace(a.Tag))
 }
+
+// HashTags returns hashtags of all tags of media separated by space, hashtag of topic tag is first.
+// Without Tags it's the same as HashTag.
+func (a Audio) HashTags() string {
+	tags := []string{a.HashTag()}
+	for _, tag := range a.Tags {
+		if tag == a.Tag {
+			continue
+		}
+		tags = append(tags, Audio{Tag: tag}.HashTag())
+	}
+	return strings.Join(tags, " ")
+}
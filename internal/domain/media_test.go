@@ -143,3 +143,11 @@ func TestAudio_Exist(t *testing.T) {
 		})
 	}
 }
+
+func TestAudio_HashTags(t *testing.T) {
+	a := Audio{Tag: "Бхагавад-гита"}
+	require.Equal(t, "#Бхагавад_гита", a.HashTags())
+
+	a.Tags = []string{"Шримад-Бхагаватам", "Бхагавад-гита", "Праздники"}
+	require.Equal(t, "#Бхагавад_гита #Шримад_Бхагаватам #Праздники", a.HashTags())
+}
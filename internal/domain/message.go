@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// Document is telegram document of published media. It implements FileLocation
+// of gotd, so media can be sent again without uploading file.
+type Document struct {
+	ID            int64
+	AccessHash    int64
+	FileReference []byte
+}
+
+func (d Document) GetID() int64 {
+	return d.ID
+}
+
+func (d Document) GetAccessHash() int64 {
+	return d.AccessHash
+}
+
+func (d Document) GetFileReference() []byte {
+	return d.FileReference
+}
+
+const (
+	MultiTagSeparate = "separate" // media is posted in topic of each tag, default
+	MultiTagForward  = "forward"  // media is posted once, message is forwarded to topics of other tags
+	MultiTagDocument = "document" // media is posted once, document is sent to topics of other tags
+)
+
+// Message is published message with media, see tg_messages.
+type Message struct {
+	ID              uint64 // tg_messages.id
+	OriginID        uint64 // tg_messages.id of message forwarded or sent again to topic, 0 - media was posted
+	MediaID         int    // media.id
+	Title           string // media.title
+	TopicID         uint64 // tg_topics.id
+	MessageThreadID int
+	Tag             string
+	MessageID       int // ID of message in group
+	Document        Document
+	Created         time.Time
+}
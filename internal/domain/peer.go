@@ -0,0 +1,31 @@
+package domain
+
+// chatIDPrefix is added to MTProto ID of channel to get chat ID of bot API.
+const chatIDPrefix = -1000000000000
+
+// Peer is resolved channel or supergroup, see tg resolve.
+type Peer struct {
+	ChannelID  int64 // MTProto ID without -100 prefix, e.g. 1234567890
+	AccessHash int64 // access hash of channel for the bot
+	Title      string
+	Username   string // empty for private chats
+	Broadcast  bool   // broadcast channel, otherwise supergroup
+	Forum      bool   // supergroup with topics
+}
+
+// ChatID returns chat ID of bot API, e.g. -1001234567890.
+func (p Peer) ChatID() int64 {
+	return chatIDPrefix - p.ChannelID
+}
+
+// ChannelID returns MTProto ID of channel by chat ID of bot API, e.g. 1234567890 for -1001234567890.
+// Positive ID is returned as is, 0 is returned for chat ID of user or basic group.
+func ChannelID(chatID int64) int64 {
+	switch {
+	case chatID < chatIDPrefix:
+		return chatIDPrefix - chatID
+	case chatID > 0:
+		return chatID
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package domain
+
+// Priority lanes of queue. Media with higher priority are published first,
+// so fresh media do not wait behind historical backfill.
+const (
+	PriorityBackfill = 0   // default for media populated from archive
+	PriorityFresh    = 100 // media tagged right now, hardcoded in trigger copy_media_tag_to_queue of schema/tg.sql
+)
+
+// QueueItem is a media waiting in tg_queue to be published to a topic.
+type QueueItem struct {
+	Priority  int
+	Topic     string // tg_topics.name
+	Published bool   // media already has telegram single instance link
+	Audio
+}
+
+// QueueStats is number of media in queues of a tg_config.
+type QueueStats struct {
+	Queue  int // waiting in tg_queue
+	Failed int // moved to tg_queue_failed
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Subscription of listener to tag, new media of tag are sent to listener by direct message.
+type Subscription struct {
+	ConfigID   int // tg_config.id of bot, 0 - bot is not bound to config
+	UserID     int64
+	AccessHash int64 // access hash of user for the bot
+	TagID      int
+}
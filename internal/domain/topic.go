@@ -12,4 +12,7 @@ type Topic struct {
 	Tag               string
 	IconCustomEmojiID *string
 	CreatedAt         *time.Time
+	Delivery          DeliveryPolicy
+	ConfigID          int  // tg_config.id, 0 - topic is not bound to config
+	Paused            bool // publishing is paused, media stays in queue
 }
@@ -0,0 +1,100 @@
+// Package logging sets up zerolog logger of application: console or JSON output,
+// log file with rotation and levels of components, e.g. gotd logs at info level
+// while the rest of application logs at debug level.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Components with own log level, see config.Log.Levels.
+const (
+	ComponentGotd     = "gotd"     // MTProto library, see Zap
+	ComponentMTProto  = "mtproto"  // MTProto session and publishing
+	ComponentPipeline = "pipeline" // queue processing
+	ComponentHTTP     = "http"     // metrics and status server
+	ComponentBot      = "bot"      // commands of listeners
+)
+
+// ParseLevel parses level of config, empty level is debug.
+func ParseLevel(level string) (zerolog.Level, error) {
+	switch level {
+	case "", "debug":
+		return zerolog.DebugLevel, nil
+	case "info":
+		return zerolog.InfoLevel, nil
+	case "warn":
+		return zerolog.WarnLevel, nil
+	case "error":
+		return zerolog.ErrorLevel, nil
+	default:
+		return zerolog.NoLevel, fmt.Errorf("unknown level %q, expected debug, info, warn or error", level)
+	}
+}
+
+var (
+	mu     sync.RWMutex
+	levels = map[string]zerolog.Level{}
+)
+
+// Setup sets global logger writing to stderr and to file. Level is default level
+// of application, components can have their own levels. File is rotated by size
+// if c.MaxSize is set. Returned closer closes log file.
+// Invalid levels are ignored to keep logging, they are reported by config.Log.Validate.
+func Setup(file, level string, c config.Log) (io.Closer, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		l = zerolog.DebugLevel
+	}
+
+	var out io.WriteCloser
+	if c.MaxSize > 0 {
+		out = &lumberjack.Logger{
+			Filename:   file,
+			MaxSize:    c.MaxSize,
+			MaxBackups: c.MaxBackups,
+			MaxAge:     c.MaxAge,
+			Compress:   c.Compress,
+		}
+	} else if out, err = os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664); err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+
+	var console io.Writer = zerolog.ConsoleWriter{Out: os.Stderr}
+	if c.Format == config.LogFormatJSON {
+		console = os.Stderr
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	levels = make(map[string]zerolog.Level, len(c.Levels))
+	for component, level := range c.Levels {
+		if l, err := ParseLevel(level); err == nil {
+			levels[component] = l
+		}
+	}
+
+	log.Logger = zerolog.New(zerolog.MultiLevelWriter(console, out)).Level(l).With().Timestamp().Logger()
+	return out, nil
+}
+
+// For returns logger of component with its level, see config.Log.Levels.
+// It must be called after Setup, loggers are not updated by next Setup.
+func For(component string) zerolog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	l := log.With().Str("component", component).Logger()
+	if level, ok := levels[component]; ok {
+		l = l.Level(level)
+	}
+	return l
+}
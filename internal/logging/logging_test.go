@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestFor(t *testing.T) {
+	c, err := Setup(filepath.Join(t.TempDir(), "tg.log"), "info", config.Log{
+		Format:  config.LogFormatJSON,
+		Levels:  map[string]string{ComponentGotd: "error"},
+		MaxSize: 1,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, zerolog.InfoLevel, log.Logger.GetLevel())
+	require.Equal(t, zerolog.ErrorLevel, For(ComponentGotd).GetLevel())
+	require.Equal(t, zerolog.InfoLevel, For(ComponentPipeline).GetLevel())
+}
+
+func TestZap(t *testing.T) {
+	var buf bytes.Buffer
+	l := Zap(zerolog.New(&buf).Level(zerolog.InfoLevel)).Named("conn").With(zap.Int("dc", 2))
+
+	l.Debug("skipped")
+	require.Empty(t, buf.String())
+
+	l.Warn("reconnect", zap.String("reason", "timeout"))
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, map[string]any{
+		"level":   "warn",
+		"logger":  "conn",
+		"dc":      float64(2),
+		"reason":  "timeout",
+		"message": "reconnect",
+	}, entry)
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Zap returns zap logger which writes to zerolog logger l, it's used by gotd.
+// Level of l is applied to zap entries.
+func Zap(l zerolog.Logger) *zap.Logger {
+	return zap.New(&zapCore{log: l})
+}
+
+// zapCore is zapcore.Core backed by zerolog.
+type zapCore struct {
+	log    zerolog.Logger
+	fields []zapcore.Field
+}
+
+func (c *zapCore) Enabled(level zapcore.Level) bool {
+	return zerologLevel(level) >= c.log.GetLevel()
+}
+
+func (c *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &zapCore{
+		log:    c.log,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *zapCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c *zapCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	ev := c.log.WithLevel(zerologLevel(e.Level))
+	if e.LoggerName != "" {
+		ev = ev.Str("logger", e.LoggerName)
+	}
+	ev.Fields(enc.Fields).Msg(e.Message)
+	return nil
+}
+
+func (c *zapCore) Sync() error {
+	return nil
+}
+
+func zerologLevel(level zapcore.Level) zerolog.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return zerolog.DebugLevel
+	case zapcore.InfoLevel:
+		return zerolog.InfoLevel
+	case zapcore.WarnLevel:
+		return zerolog.WarnLevel
+	case zapcore.ErrorLevel:
+		return zerolog.ErrorLevel
+	default:
+		// DPanic, Panic and Fatal entries are logged, zap panics or exits itself
+		return zerolog.ErrorLevel
+	}
+}
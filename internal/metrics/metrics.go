@@ -0,0 +1,155 @@
+// Package metrics exposes prometheus metrics of publishing service.
+// All metrics are labeled by slug of tg_config, so pipelines of several bots can be told apart.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tg"
+
+var (
+	queueSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_size",
+		Help:      "Number of media in publish queue.",
+	}, []string{"slug"})
+
+	failedQueueSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "failed_queue_size",
+		Help:      "Number of media in failed queue.",
+	}, []string{"slug"})
+
+	uploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Number of published media by topic and result: succeeded or failed.",
+	}, []string{"slug", "topic", "result"})
+
+	uploadedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Size of audio files uploaded to telegram DC.",
+	}, []string{"slug"})
+
+	uploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Time of publishing media, including upload of audio file.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10), // 0.5s - 4m
+	}, []string{"slug"})
+
+	mirrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mirrors_total",
+		Help:      "Number of published media sent to destinations of topics by destination and result: succeeded or failed.",
+	}, []string{"slug", "destination", "result"})
+
+	singleInstance = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "single_instance_total",
+		Help:      "Number of published media by source of file: reused (single instance hit) or uploaded.",
+	}, []string{"slug", "result"})
+
+	floodWaits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flood_waits_total",
+		Help:      "Number of FLOOD_WAIT errors from telegram DC.",
+	}, []string{"slug"})
+
+	floodWaitSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flood_wait_seconds_total",
+		Help:      "Total time to wait requested by FLOOD_WAIT errors.",
+	}, []string{"slug"})
+
+	sessionRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_restarts_total",
+		Help:      "Number of MTProto sessions closed with error and restarted.",
+	}, []string{"slug"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		queueSize,
+		failedQueueSize,
+		uploads,
+		uploadedBytes,
+		uploadDuration,
+		mirrors,
+		singleInstance,
+		floodWaits,
+		floodWaitSeconds,
+		sessionRestarts,
+	)
+}
+
+// Handler serves metrics in prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Pipeline records metrics of pipeline of one tg_config.
+type Pipeline struct {
+	slug string
+}
+
+func For(slug string) Pipeline {
+	return Pipeline{slug: slug}
+}
+
+func (m Pipeline) SetQueueSize(queue, failed int) {
+	queueSize.WithLabelValues(m.slug).Set(float64(queue))
+	failedQueueSize.WithLabelValues(m.slug).Set(float64(failed))
+}
+
+// Published records successfully published media. bytes is size of uploaded file, 0 if file was reused.
+func (m Pipeline) Published(topic string, bytes int64, d time.Duration) {
+	uploads.WithLabelValues(m.slug, topic, "succeeded").Inc()
+	uploadDuration.WithLabelValues(m.slug).Observe(d.Seconds())
+	if bytes > 0 {
+		uploadedBytes.WithLabelValues(m.slug).Add(float64(bytes))
+	}
+}
+
+func (m Pipeline) Failed(topic string) {
+	uploads.WithLabelValues(m.slug, topic, "failed").Inc()
+}
+
+// Mirrored records media sent to destination of topic, ok is false if it wasn't sent.
+func (m Pipeline) Mirrored(destination string, ok bool) {
+	result := "succeeded"
+	if !ok {
+		result = "failed"
+	}
+	mirrors.WithLabelValues(m.slug, destination, result).Inc()
+}
+
+// SingleInstance records if already uploaded file was reused or file was uploaded again.
+func (m Pipeline) SingleInstance(reused bool) {
+	result := "uploaded"
+	if reused {
+		result = "reused"
+	}
+	singleInstance.WithLabelValues(m.slug, result).Inc()
+}
+
+func (m Pipeline) FloodWait(d time.Duration) {
+	floodWaits.WithLabelValues(m.slug).Inc()
+	floodWaitSeconds.WithLabelValues(m.slug).Add(d.Seconds())
+}
+
+func (m Pipeline) SessionRestarted() {
+	sessionRestarts.WithLabelValues(m.slug).Inc()
+}
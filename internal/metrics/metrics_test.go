@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	m := For("test.ru")
+	m.SetQueueSize(7, 2)
+	m.Published("Бхагавад-гита", 1024, 3*time.Second)
+	m.Published("Бхагавад-гита", 0, time.Second)
+	m.Failed("Бхагавад-гита")
+	m.Mirrored("Канал", true)
+	m.Mirrored("Канал", false)
+	m.SingleInstance(true)
+	m.SingleInstance(false)
+	m.FloodWait(30 * time.Second)
+	m.FloodWait(15 * time.Second)
+	m.SessionRestarted()
+
+	require.Equal(t, 7.0, testutil.ToFloat64(queueSize.WithLabelValues("test.ru")))
+	require.Equal(t, 2.0, testutil.ToFloat64(failedQueueSize.WithLabelValues("test.ru")))
+	require.Equal(t, 2.0, testutil.ToFloat64(uploads.WithLabelValues("test.ru", "Бхагавад-гита", "succeeded")))
+	require.Equal(t, 1.0, testutil.ToFloat64(uploads.WithLabelValues("test.ru", "Бхагавад-гита", "failed")))
+	require.Equal(t, 1024.0, testutil.ToFloat64(uploadedBytes.WithLabelValues("test.ru")))
+	require.Equal(t, 1.0, testutil.ToFloat64(mirrors.WithLabelValues("test.ru", "Канал", "failed")))
+	require.Equal(t, 1.0, testutil.ToFloat64(singleInstance.WithLabelValues("test.ru", "reused")))
+	require.Equal(t, 2.0, testutil.ToFloat64(floodWaits.WithLabelValues("test.ru")))
+	require.Equal(t, 45.0, testutil.ToFloat64(floodWaitSeconds.WithLabelValues("test.ru")))
+	require.Equal(t, 1.0, testutil.ToFloat64(sessionRestarts.WithLabelValues("test.ru")))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `tg_upload_duration_seconds_count{slug="test.ru"} 2`)
+}
@@ -0,0 +1,27 @@
+package mtproto
+
+import (
+	"regexp"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/styling"
+)
+
+var hashtag = regexp.MustCompile(`#[\p{L}\p{N}_]+`)
+
+// styledCaption returns caption text with hashtags marked by entities.
+func styledCaption(text string) []message.StyledTextOption {
+	var opts []message.StyledTextOption
+	pos := 0
+	for _, m := range hashtag.FindAllStringIndex(text, -1) {
+		if m[0] > pos {
+			opts = append(opts, styling.Plain(text[pos:m[0]]))
+		}
+		opts = append(opts, styling.Hashtag(text[m[0]:m[1]]))
+		pos = m[1]
+	}
+	if pos < len(text) {
+		opts = append(opts, styling.Plain(text[pos:]))
+	}
+	return opts
+}
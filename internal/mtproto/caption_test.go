@@ -0,0 +1,23 @@
+package mtproto
+
+import (
+	"testing"
+
+	"github.com/gotd/td/telegram/message/entity"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStyledCaption(t *testing.T) {
+	var b entity.Builder
+	require.NoError(t, styling.Perform(&b, styledCaption("Бхагавад-гита 2.47\n#Бхагавад_гита #ШП")...))
+
+	text, entities := b.Complete()
+	require.Equal(t, "Бхагавад-гита 2.47\n#Бхагавад_гита #ШП", text)
+	require.Len(t, entities, 2)
+	require.IsType(t, &tg.MessageEntityHashtag{}, entities[0])
+	require.Equal(t, 19, entities[0].GetOffset())
+	require.Equal(t, 14, entities[0].GetLength())
+	require.Equal(t, 34, entities[1].GetOffset())
+}
@@ -0,0 +1,152 @@
+package mtproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/auth/qrlogin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"rsc.io/qr"
+)
+
+// LoginParams are answers of user for tg login.
+type LoginParams struct {
+	Phone    string                                              // phone number, QR code is used if empty
+	Code     func(ctx context.Context) (string, error)           // reads code sent to Telegram app or by SMS
+	Password func(ctx context.Context) (string, error)           // reads password of two-step verification
+	ShowQR   func(ctx context.Context, qrcode, url string) error // shows QR code rendered by QRText, it's called again when code expires
+}
+
+// Login authorizes user account by phone code or QR code and saves session to p.SessionFile,
+// so StartSession uses it instead of bot token. Logged in user is returned, session which
+// is already authorized isn't changed.
+func Login(ctx context.Context, p SesstionParams, l LoginParams) (*tg.User, error) {
+	if p.SessionFile == "" {
+		return nil, errors.New("session file is required to login")
+	}
+	d := tg.NewUpdateDispatcher()
+	p.UpdateHandler = d
+	loggedIn := qrlogin.OnLoginToken(d)
+
+	c, err := New(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var user *tg.User
+	err = c.client.Run(ctx, func(ctx context.Context) error {
+		status, err := c.client.Auth().Status(ctx)
+		if err != nil {
+			return fmt.Errorf("get auth status: %w", err)
+		}
+		if status.Authorized {
+			if status.User.Bot {
+				return errors.New("session belongs to bot, remove session file to login as user")
+			}
+			user = status.User
+			return nil
+		}
+
+		if l.Phone != "" {
+			flow := auth.NewFlow(loginAuthenticator{l}, auth.SendCodeOptions{})
+			if err := flow.Run(ctx, c.client.Auth()); err != nil {
+				return fmt.Errorf("login by phone: %w", err)
+			}
+		} else if err := c.loginQR(ctx, loggedIn, l); err != nil {
+			return fmt.Errorf("login by QR code: %w", err)
+		}
+
+		if user, err = c.client.Self(ctx); err != nil {
+			return fmt.Errorf("get logged in user: %w", err)
+		}
+		return nil
+	})
+	return user, err
+}
+
+// loginQR shows QR code until it's scanned by Telegram app of logged in user.
+func (c *MTProtoClient) loginQR(ctx context.Context, loggedIn qrlogin.LoggedIn, l LoginParams) error {
+	_, err := c.client.QR().Auth(ctx, loggedIn, func(ctx context.Context, token qrlogin.Token) error {
+		text, err := QRText(token.URL())
+		if err != nil {
+			return err
+		}
+		return l.ShowQR(ctx, text, token.URL())
+	})
+	if !tgerr.Is(err, "SESSION_PASSWORD_NEEDED") {
+		return err
+	}
+
+	password, err := l.Password(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.Auth().Password(ctx, password); err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+	return nil
+}
+
+// loginAuthenticator asks user for code and password of phone login.
+type loginAuthenticator struct {
+	l LoginParams
+}
+
+func (a loginAuthenticator) Phone(context.Context) (string, error) {
+	return a.l.Phone, nil
+}
+
+func (a loginAuthenticator) Password(ctx context.Context) (string, error) {
+	return a.l.Password(ctx)
+}
+
+func (a loginAuthenticator) Code(ctx context.Context, _ *tg.AuthSentCode) (string, error) {
+	return a.l.Code(ctx)
+}
+
+func (a loginAuthenticator) AcceptTermsOfService(context.Context, tg.HelpTermsOfService) error {
+	return errors.New("account not found, sign up in Telegram app first")
+}
+
+func (a loginAuthenticator) SignUp(context.Context) (auth.UserInfo, error) {
+	return auth.UserInfo{}, errors.New("account not found, sign up in Telegram app first")
+}
+
+// QRText renders QR code of text with block characters, two rows of modules per line.
+// Light modules are printed, so code is readable on dark terminal.
+func QRText(text string) (string, error) {
+	code, err := qr.Encode(text, qr.L)
+	if err != nil {
+		return "", fmt.Errorf("encode QR code: %w", err)
+	}
+
+	const quiet = 2 // light border around code
+	light := func(x, y int) bool {
+		return !code.Black(x-quiet, y-quiet)
+	}
+
+	size := code.Size + 2*quiet
+	var b strings.Builder
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			top, bottom := light(x, y), y+1 < size && light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,22 @@
+package mtproto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRText(t *testing.T) {
+	text, err := QRText("tg://login?token=AQAB")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := len([]rune(lines[0]))
+	require.Equal(t, (width+1)/2, len(lines)) // two rows of modules per line
+	for _, l := range lines {
+		require.Len(t, []rune(l), width)
+	}
+	require.Equal(t, strings.Repeat("█", width), lines[0]) // quiet zone
+	require.Contains(t, text, " ")                         // dark modules
+}
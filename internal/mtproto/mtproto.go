@@ -2,25 +2,38 @@ package mtproto
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"path/filepath"
 	"time"
 
 	"github.com/gotd/contrib/middleware/floodwait"
-	"github.com/gotd/contrib/middleware/ratelimit"
+	"github.com/gotd/contrib/oteltg"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/message/styling"
 	"github.com/gotd/td/telegram/uploader"
 	"github.com/gotd/td/tg"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"gitlab.com/bvgm/tg/internal/config"
 	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/logging"
+	"gitlab.com/bvgm/tg/internal/proxy"
+	"gitlab.com/bvgm/tg/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
 
 const defaultRateLimit = time.Second
 
+var tracer = otel.Tracer("gitlab.com/bvgm/tg/internal/mtproto")
+
 type SesstionParams struct {
 	TgAppID        int
 	TgAppHash      string
@@ -29,6 +42,10 @@ type SesstionParams struct {
 	TgBotToken     string
 	Threads        int
 	RateLimit      time.Duration
+	OnFloodWait    func(wait time.Duration) // optional, called on every FLOOD_WAIT error
+	UpdateHandler  telegram.UpdateHandler   // optional, handles incoming updates, e.g. commands of listeners
+	SessionFile    string                   // optional, session of user account saved by tg login is used instead of bot token
+	Proxy          config.Proxy             // optional, SOCKS5 or HTTP proxy or MTProxy of connections to DCs
 }
 
 type SingleInstanceFile struct {
@@ -44,65 +61,92 @@ func (sif *SingleInstanceFile) create() (tg.InputFileClass, error) {
 	return file, nil
 }
 
-type PublishAudioFunc func(audio domain.Audio, tok *string) (string, error)
+// Published is result of publishing audio.
+type Published struct {
+	SingleInstance string // serialized uploaded file, see SingleInstanceFile
+	MessageID      int    // ID of message in group, 0 if it's not found in response
+	Document       domain.Document
+}
+
+// PublishAudioFunc publishes audio, ctx is used for tracing only, session context is used for requests.
+type PublishAudioFunc func(ctx context.Context, audio domain.Audio, tok *string) (Published, error)
 
 type MTProtoClient struct {
-	client *telegram.Client
-	sess   SesstionParams
-	sCtx   context.Context
-	logger *zap.Logger
-	waiter *floodwait.Waiter
+	client  *telegram.Client
+	sess    SesstionParams
+	sCtx    context.Context
+	logger  *zap.Logger
+	log     zerolog.Logger
+	waiter  *floodwait.Waiter
+	limiter *rate.Limiter
+	peers   peerCache // resolved channels, see ResolvePeer
 }
 
 func (c *MTProtoClient) Client() *telegram.Client {
 	return c.client
 }
 
+// API returns raw telegram API, it can be used in running session only.
+func (c *MTProtoClient) API() *tg.Client {
+	return c.client.API()
+}
+
 func New(ctx context.Context, p SesstionParams) (*MTProtoClient, error) {
 
-	logger, err := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zap.InfoLevel),
-		Development: false,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:         "console",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stderr"},
-		ErrorOutputPaths: []string{"stderr"},
-	}.Build()
-	if err != nil {
-		return nil, fmt.Errorf("create zap logger: %w", err)
-	}
+	l := logging.For(logging.ComponentMTProto)
+	// gotd logs to the same outputs as application, with its own level
+	logger := logging.Zap(logging.For(logging.ComponentGotd))
 
 	waiter := floodwait.NewWaiter().
 		WithMaxWait(time.Hour).
 		WithCallback(func(ctx context.Context, wait floodwait.FloodWait) {
 			// Notifying about flood wait.
-			fmt.Println("Got FLOOD_WAIT. Will retry after ", wait.Duration.String())
-			log.Warn().Dur("wait", wait.Duration).Msg("Flood wait")
+			l.Warn().Dur("wait", wait.Duration).Msg("Flood wait")
+			trace.SpanFromContext(ctx).AddEvent("flood wait", trace.WithAttributes(
+				attribute.String("wait", wait.Duration.String()),
+			))
+			if p.OnFloodWait != nil {
+				p.OnFloodWait(wait.Duration)
+			}
 		})
 
 	if p.RateLimit == 0 {
 		p.RateLimit = defaultRateLimit
 	}
+	limiter := rate.NewLimiter(rate.Every(p.RateLimit), 5)
+	otelMiddleware, err := oteltg.New(otel.GetMeterProvider(), otel.GetTracerProvider())
+	if err != nil {
+		return nil, fmt.Errorf("create tracing middleware: %w", err)
+	}
+	resolver, err := proxy.Resolver(p.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("create proxy resolver: %w", err)
+	}
+	// bot session is authorized again on each start, user session can't be
+	var storage telegram.SessionStorage = &SessionCache{}
+	if p.SessionFile != "" {
+		storage = &session.FileStorage{Path: p.SessionFile}
+	}
 	client := telegram.NewClient(
 		p.TgAppID,
 		p.TgAppHash,
 		telegram.Options{
 			OnDead: func() {
-				log.Error().Msg("telegram client dead")
+				l.Error().Msg("telegram client dead")
 			},
 			RetryInterval:   time.Second * 7,
 			MaxRetries:      5,
 			DialTimeout:     time.Second * 10,
 			ExchangeTimeout: time.Second * 10,
-			SessionStorage:  &SessionCache{},
+			SessionStorage:  storage,
+			Resolver:        resolver,
+			UpdateHandler:   p.UpdateHandler,
 			Logger:          logger,
 			Middlewares: []telegram.Middleware{
+				// Span of every rpc request including waiting for rate limit and flood wait.
+				otelMiddleware,
 				// Setting up general rate limits to less likely get flood wait errors.
-				ratelimit.New(rate.Every(p.RateLimit), 5),
+				rateLimit(limiter),
 				// Handler of FLOOD_WAIT that will automatically retry request.
 				waiter,
 			},
@@ -111,23 +155,49 @@ func New(ctx context.Context, p SesstionParams) (*MTProtoClient, error) {
 	// client.Pool(5)
 
 	return &MTProtoClient{
-		client: client,
-		sess:   p,
-		logger: logger,
-		waiter: waiter,
+		client:  client,
+		sess:    p,
+		logger:  logger,
+		log:     l,
+		waiter:  waiter,
+		limiter: limiter,
 	}, nil
 }
 
+// rateLimit is middleware that throttles rpc requests. Unlike contrib ratelimit
+// its limit can be changed in running session, see SetRateLimit.
+func rateLimit(l *rate.Limiter) telegram.MiddlewareFunc {
+	return func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			if err := l.Wait(ctx); err != nil {
+				return fmt.Errorf("rate limit: %w", err)
+			}
+			return next.Invoke(ctx, input, output)
+		}
+	}
+}
+
+// SetRateLimit changes minimal interval between rpc requests without restarting session.
+func (c *MTProtoClient) SetRateLimit(d time.Duration) {
+	if d == 0 {
+		d = defaultRateLimit
+	}
+	if c.limiter.Limit() != rate.Every(d) {
+		c.limiter.SetLimit(rate.Every(d))
+		c.log.Info().Dur("rate limit", d).Msg("rate limit changed")
+	}
+}
+
 func (c *MTProtoClient) Close() {
 
 	if err := c.logger.Sync(); err != nil {
-		log.Error().Err(err).Msg("close zap logger")
+		c.log.Error().Err(err).Msg("close zap logger")
 	}
 }
 
 // ctx - application context.
 func (c *MTProtoClient) StartSession(ctx context.Context, queueProcessor func(PublishAudioFunc) error) error {
-	log.Info().Msg("creating mtproto session")
+	c.log.Info().Msg("creating mtproto session")
 
 	err := c.waiter.Run(ctx, func(ctx context.Context) error {
 		return c.client.Run(ctx, func(ctx context.Context) error {
@@ -141,6 +211,9 @@ func (c *MTProtoClient) StartSession(ctx context.Context, queueProcessor func(Pu
 			// Can be already authenticated if we have valid session in
 			// session storage.
 			if !status.Authorized {
+				if c.sess.SessionFile != "" {
+					return fmt.Errorf("user session %s is not authorized, run tg login", c.sess.SessionFile)
+				}
 				// Otherwise, perform bot authentication.
 				if _, err := c.client.Auth().Bot(ctx, c.sess.TgBotToken); err != nil {
 					return fmt.Errorf("bot auth: %w", err)
@@ -150,7 +223,7 @@ func (c *MTProtoClient) StartSession(ctx context.Context, queueProcessor func(Pu
 			if err != nil {
 				return fmt.Errorf("get auth status: %w", err)
 			}
-			log.Info().Interface("status", status).Msg("Authenticated")
+			c.log.Info().Interface("status", status).Msg("Authenticated")
 
 			return queueProcessor(c.PublishAudio)
 		})
@@ -160,59 +233,92 @@ func (c *MTProtoClient) StartSession(ctx context.Context, queueProcessor func(Pu
 		return fmt.Errorf("stop session: %w", err)
 	}
 
-	log.Info().Msg("session closed")
+	c.log.Info().Msg("session closed")
 	return nil
 }
 
 // ctx - Must be sesstion context
 // https://core.telegram.org/api/forum
 // https://core.telegram.org/constructor/inputReplyToMessage - to send to topic
-func (c *MTProtoClient) PublishAudio(audio domain.Audio, tok *string) (string, error) {
-	log.Info().Bool("single_instance", tok != nil).Msg("sending media to group")
-
-	var (
-		f   tg.InputFileClass
-		err error
-	)
+func (c *MTProtoClient) PublishAudio(ctx context.Context, audio domain.Audio, tok *string) (res Published, err error) {
+	c.log.Info().Bool("single_instance", tok != nil).Bool("silent", audio.Silent).Msg("sending media to group")
+
+	_, span := tracer.Start(ctx, "publish audio", trace.WithAttributes(
+		attribute.Int("media.id", audio.MediaID),
+		attribute.String("media.title", audio.Title),
+		attribute.String("media.tag", audio.Tag),
+		attribute.Bool("single_instance", tok != nil),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+	// requests are canceled with session, spans are children of publish span
+	sCtx := trace.ContextWithSpan(c.sCtx, span)
 
+	var f tg.InputFileClass
 	if tok != nil {
+		_, decode := tracer.Start(sCtx, "single instance decode")
 		sif := &SingleInstanceFile{SerializedObject: *tok, MediaID: audio.MediaID}
 		f, err = sif.create()
 		if err != nil {
-			log.Warn().Err(err).Msg("single instance file object exist, but can't create. Try to uplad again.")
+			tracing.Fail(decode, err)
+			c.log.Warn().Err(err).Msg("single instance file object exist, but can't create. Try to uplad again.")
 		}
+		decode.End()
 	}
 
 	if f == nil {
+		uCtx, upload := tracer.Start(sCtx, "upload", trace.WithAttributes(
+			attribute.String("file", filepath.Base(audio.Path)),
+			attribute.Int("threads", c.sess.Threads),
+		))
 		// Helper for uploading. Automatically uses big file upload when needed.
 		f, err = uploader.
 			NewUploader(c.client.API()).
 			WithThreads(c.sess.Threads).
-			FromPath(c.sCtx, audio.Path)
+			FromPath(uCtx, audio.Path)
 		if err != nil {
-			return "", fmt.Errorf("upload %q: %w", audio.Path, err)
+			tracing.Fail(upload, err)
+			upload.End()
+			return res, fmt.Errorf("upload %q: %w", audio.Path, err)
 		}
+		upload.End()
 	}
 
-	siID, err := Marshal(f)
+	res.SingleInstance, err = Marshal(f)
 	if err != nil {
-		return "", fmt.Errorf("serialize uploaded audio for single instance: %w", err)
+		return res, fmt.Errorf("serialize uploaded audio for single instance: %w", err)
 	}
 
 	// Helper for sending messages.
 	sender := message.NewSender(c.client.API())
 
-	r := sender.To(&tg.InputPeerChannel{
-		ChannelID:  c.sess.MtprotoGroupID,
-		AccessHash: c.sess.AccessHash,
-	})
+	group, err := c.inputChannel(sCtx, c.group())
+	if err != nil {
+		return res, err
+	}
+	r := sender.To(group)
 
 	caption := []message.StyledTextOption{styling.Plain(audio.Title), styling.Plain("\n")}
 	caption = append(caption, styling.Hashtag(audio.HashTag()))
+	if audio.Caption != "" {
+		caption = styledCaption(audio.Caption)
+	}
+
+	b := r.Reply(audio.MessageThreadID)
+	if audio.Silent {
+		b = b.Silent()
+	}
+
+	sendCtx, send := tracer.Start(sCtx, "send")
+	defer send.End()
 
 	// https://github.com/gotd/td/pull/1597 - message.Audio does not allow to set filename attribute
-	if _, err := r.Reply(audio.MessageThreadID).
-		Media(c.sCtx, message.UploadedDocument(f,
+	upd, err := b.
+		Media(sendCtx, message.UploadedDocument(f,
 			caption...,
 		).MIME(message.DefaultAudioMIME).
 			Filename(filepath.Base(audio.Path)).
@@ -225,34 +331,106 @@ func (c *MTProtoClient) PublishAudio(audio domain.Audio, tok *string) (string, e
 					}
 					return int(audio.Duration.Seconds())
 				}(),
-			})); err != nil {
-		return "", fmt.Errorf("send media: %w", err)
+			}))
+	if err != nil {
+		tracing.Fail(send, err)
+		return res, fmt.Errorf("send media: %w", err)
 	}
 
-	return siID, nil
+	if msg, ok := sentMessage(upd); ok {
+		res.MessageID = msg.ID
+		res.Document, _ = messageDocument(msg)
+	}
+	if res.Document.ID == 0 {
+		c.log.Warn().Str("title", audio.Title).Msg("document of sent message not found, it can't be sent again without upload")
+	}
+	return res, nil
 }
 
-// example to get channel with ID:
-// channel, err := getChannel(ctx, client.API(), cfg.GetInt64("telegram.mtproto_group_id"))
-//
-//	if err != nil {
-//		return fmt.Errorf("get channel: %w", err)
-//	}
-func (c *MTProtoClient) GetChannelInfo(ctx context.Context, channelID int64) (*tg.Channel, error) {
-	inputChannel := &tg.InputChannel{
-		ChannelID:  channelID,
-		AccessHash: 0,
+// SendDocument sends already uploaded document to destination, e.g. mirror channel of topic,
+// file isn't uploaded again. ID of sent message is returned, 0 if it's not found in response.
+// Like PublishAudio ctx is used for tracing only, session context is used for requests.
+func (c *MTProtoClient) SendDocument(ctx context.Context, dst domain.Destination, doc domain.Document, text string, silent bool) (id int, err error) {
+	_, span := tracer.Start(ctx, "send document", trace.WithAttributes(
+		attribute.String("destination", dst.Name),
+		attribute.Int64("channel.id", dst.ChannelID),
+		attribute.Int("message_thread_id", dst.MessageThreadID),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+	sCtx := trace.ContextWithSpan(c.sCtx, span)
+
+	peer, err := c.inputChannel(sCtx, dst)
+	if err != nil {
+		return 0, err
+	}
+	b := &message.NewSender(c.client.API()).To(peer).Builder
+	if dst.MessageThreadID != 0 {
+		b = b.Reply(dst.MessageThreadID)
+	}
+	if silent {
+		b = b.Silent()
 	}
-	channels, err := c.client.API().ChannelsGetChannels(ctx, []tg.InputChannelClass{inputChannel})
 
+	upd, err := b.Media(sCtx, message.Document(doc, styledCaption(text)...))
 	if err != nil {
-		return nil, fmt.Errorf("fetch channel: %w", err)
+		return 0, fmt.Errorf("send document to %s: %w", dst.Name, err)
 	}
+	if msg, ok := sentMessage(upd); ok {
+		id = msg.ID
+	}
+	return id, nil
+}
 
-	if len(channels.GetChats()) == 0 {
-		return nil, fmt.Errorf("no channels found")
+// ForwardMessage forwards message of group of session to destination, e.g. to another topic
+// of the group. ID of forwarded message is returned, 0 if it's not found in response.
+// Like PublishAudio ctx is used for tracing only, session context is used for requests.
+func (c *MTProtoClient) ForwardMessage(ctx context.Context, dst domain.Destination, msgID int, silent bool) (id int, err error) {
+	_, span := tracer.Start(ctx, "forward message", trace.WithAttributes(
+		attribute.String("destination", dst.Name),
+		attribute.Int64("channel.id", dst.ChannelID),
+		attribute.Int("message_thread_id", dst.MessageThreadID),
+		attribute.Int("message.id", msgID),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+	sCtx := trace.ContextWithSpan(c.sCtx, span)
+
+	group, err := c.inputChannel(sCtx, c.group())
+	if err != nil {
+		return 0, err
+	}
+	peer, err := c.inputChannel(sCtx, dst)
+	if err != nil {
+		return 0, err
+	}
+	randomID, err := crypto.RandInt64(rand.Reader)
+	if err != nil {
+		return 0, fmt.Errorf("generate random ID: %w", err)
 	}
 
-	channel := channels.GetChats()[0].(*tg.Channel)
-	return channel, nil
+	// ForwardBuilder of gotd can't forward to topic of forum, so request is made directly
+	upd, err := c.client.API().MessagesForwardMessages(sCtx, &tg.MessagesForwardMessagesRequest{
+		Silent:   silent,
+		FromPeer: group,
+		ID:       []int{msgID},
+		RandomID: []int64{randomID},
+		ToPeer:   peer,
+		TopMsgID: dst.MessageThreadID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("forward message %d to %s: %w", msgID, dst.Name, err)
+	}
+	if msg, ok := sentMessage(upd); ok {
+		id = msg.ID
+	}
+	return id, nil
 }
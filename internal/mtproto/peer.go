@@ -0,0 +1,179 @@
+package mtproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gotd/td/tg"
+	"gitlab.com/bvgm/tg/internal/domain"
+	"gitlab.com/bvgm/tg/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{3,31}$`)
+
+// ParsePeer returns username or MTProto channel ID of target. Target is @username,
+// t.me link, e.g. https://t.me/username or https://t.me/c/1234567890/12, or chat ID
+// of bot API, e.g. -1001234567890.
+func ParsePeer(target string) (username string, channelID int64, err error) {
+	t := strings.TrimSpace(target)
+	t = strings.TrimPrefix(t, "https://")
+	t = strings.TrimPrefix(t, "http://")
+
+	for _, host := range []string{"t.me/", "telegram.me/"} {
+		if !strings.HasPrefix(t, host) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(t, host), "/")
+		switch {
+		case parts[0] == "c" && len(parts) > 1:
+			id, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil || id <= 0 {
+				return "", 0, fmt.Errorf("invalid channel ID in link %q", target)
+			}
+			return "", id, nil
+		case parts[0] == "joinchat" || strings.HasPrefix(parts[0], "+"):
+			return "", 0, fmt.Errorf("invite link %q can't be resolved, use username or ID", target)
+		}
+		t = parts[0]
+	}
+
+	if id, err := strconv.ParseInt(t, 10, 64); err == nil {
+		if channelID = domain.ChannelID(id); channelID == 0 {
+			return "", 0, fmt.Errorf("%d is not ID of channel or supergroup", id)
+		}
+		return "", channelID, nil
+	}
+
+	username = strings.TrimPrefix(t, "@")
+	if !usernameRe.MatchString(username) {
+		return "", 0, fmt.Errorf("invalid username %q", target)
+	}
+	return username, 0, nil
+}
+
+// peerCache keeps peers resolved in session, so destinations without access hash
+// and repeated tg resolve don't make requests.
+type peerCache struct {
+	mu    sync.Mutex
+	peers map[string]domain.Peer // by lowercase username and by "id:" + channel ID
+}
+
+func (c *peerCache) get(key string) (domain.Peer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.peers[key]
+	return p, ok
+}
+
+func (c *peerCache) add(p domain.Peer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peers == nil {
+		c.peers = make(map[string]domain.Peer)
+	}
+	c.peers[idKey(p.ChannelID)] = p
+	if p.Username != "" {
+		c.peers[strings.ToLower(p.Username)] = p
+	}
+}
+
+func idKey(channelID int64) string {
+	return "id:" + strconv.FormatInt(channelID, 10)
+}
+
+// ResolvePeer resolves channel or supergroup, see ParsePeer for format of target.
+// Channel is resolved by ID with zero access hash, it's allowed for bots which are members
+// of channel. Resolved peers are cached for lifetime of client.
+// Like PublishAudio ctx is used for tracing only, session context is used for requests.
+func (c *MTProtoClient) ResolvePeer(ctx context.Context, target string) (p domain.Peer, err error) {
+	username, channelID, err := ParsePeer(target)
+	if err != nil {
+		return p, err
+	}
+
+	key := strings.ToLower(username)
+	if username == "" {
+		key = idKey(channelID)
+	}
+	if p, ok := c.peers.get(key); ok {
+		return p, nil
+	}
+
+	_, span := tracer.Start(ctx, "resolve peer", trace.WithAttributes(
+		attribute.String("peer", target),
+	))
+	defer func() {
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+	sCtx := trace.ContextWithSpan(c.sCtx, span)
+
+	var chats []tg.ChatClass
+	if username != "" {
+		r, err := c.client.API().ContactsResolveUsername(sCtx, &tg.ContactsResolveUsernameRequest{Username: username})
+		if err != nil {
+			return p, fmt.Errorf("resolve username %s: %w", username, err)
+		}
+		chats = r.Chats
+	} else {
+		r, err := c.client.API().ChannelsGetChannels(sCtx, []tg.InputChannelClass{
+			&tg.InputChannel{ChannelID: channelID},
+		})
+		if err != nil {
+			return p, fmt.Errorf("get channel %d: %w", channelID, err)
+		}
+		chats = r.GetChats()
+	}
+
+	for _, chat := range chats {
+		switch ch := chat.(type) {
+		case *tg.Channel:
+			p = domain.Peer{
+				ChannelID:  ch.ID,
+				AccessHash: ch.AccessHash,
+				Title:      ch.Title,
+				Username:   ch.Username,
+				Broadcast:  ch.Broadcast,
+				Forum:      ch.Forum,
+			}
+			c.peers.add(p)
+			c.log.Info().Int64("channel", p.ChannelID).Str("title", p.Title).Msg("peer resolved")
+			return p, nil
+		case *tg.ChannelForbidden:
+			return p, fmt.Errorf("access to %q is forbidden", ch.Title)
+		}
+	}
+	return p, errors.New("channel or supergroup not found, it can be user or basic group")
+}
+
+// inputChannel returns peer of destination. Destination without access hash is resolved by ID.
+func (c *MTProtoClient) inputChannel(ctx context.Context, dst domain.Destination) (*tg.InputPeerChannel, error) {
+	if dst.AccessHash == 0 {
+		p, err := c.ResolvePeer(ctx, strconv.FormatInt(dst.ChannelID, 10))
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", dst.Name, err)
+		}
+		dst.AccessHash = p.AccessHash
+	}
+	return &tg.InputPeerChannel{ChannelID: dst.ChannelID, AccessHash: dst.AccessHash}, nil
+}
+
+// GroupPeer returns peer of group of session, it's resolved by ID if access hash isn't set.
+// It must be called in running session.
+func (c *MTProtoClient) GroupPeer(ctx context.Context) (*tg.InputPeerChannel, error) {
+	return c.inputChannel(ctx, c.group())
+}
+
+// group returns group of session as destination.
+func (c *MTProtoClient) group() domain.Destination {
+	return domain.Destination{Name: "group", ChannelID: c.sess.MtprotoGroupID, AccessHash: c.sess.AccessHash}
+}
@@ -0,0 +1,61 @@
+package mtproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestParsePeer(t *testing.T) {
+	tests := []struct {
+		target    string
+		username  string
+		channelID int64
+		wantErr   bool
+	}{
+		{target: "@goswami_ru", username: "goswami_ru"},
+		{target: "goswami_ru", username: "goswami_ru"},
+		{target: "https://t.me/goswami_ru", username: "goswami_ru"},
+		{target: "t.me/goswami_ru/123", username: "goswami_ru"},
+		{target: "https://t.me/c/2586736000/12", channelID: 2586736000},
+		{target: "-1002586736000", channelID: 2586736000},
+		{target: "2586736000", channelID: 2586736000},
+		{target: "-123456", wantErr: true},
+		{target: "https://t.me/+AbCdEf", wantErr: true},
+		{target: "https://t.me/joinchat/AbCdEf", wantErr: true},
+		{target: "@ab", wantErr: true},
+		{target: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			username, channelID, err := ParsePeer(tt.target)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.username, username)
+			require.Equal(t, tt.channelID, channelID)
+		})
+	}
+}
+
+func TestPeerCache(t *testing.T) {
+	var c peerCache
+	_, ok := c.get(idKey(2586736000))
+	require.False(t, ok)
+
+	p := domain.Peer{ChannelID: 2586736000, AccessHash: 1, Username: "Goswami_Ru"}
+	c.add(p)
+
+	got, ok := c.get(idKey(2586736000))
+	require.True(t, ok)
+	require.Equal(t, p, got)
+
+	got, ok = c.get("goswami_ru")
+	require.True(t, ok)
+	require.Equal(t, p, got)
+	require.Equal(t, int64(-1002586736000), got.ChatID())
+}
@@ -0,0 +1,46 @@
+package mtproto
+
+import (
+	"github.com/gotd/td/tg"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// sentMessage returns message sent by request with upd response.
+func sentMessage(upd tg.UpdatesClass) (*tg.Message, bool) {
+	var updates []tg.UpdateClass
+	switch u := upd.(type) {
+	case *tg.Updates:
+		updates = u.Updates
+	case *tg.UpdatesCombined:
+		updates = u.Updates
+	case *tg.UpdateShort:
+		updates = []tg.UpdateClass{u.Update}
+	}
+
+	for _, u := range updates {
+		var m tg.MessageClass
+		switch u := u.(type) {
+		case *tg.UpdateNewChannelMessage:
+			m = u.Message
+		case *tg.UpdateNewMessage:
+			m = u.Message
+		}
+		if msg, ok := m.(*tg.Message); ok {
+			return msg, true
+		}
+	}
+	return nil, false
+}
+
+// messageDocument returns document of media of message.
+func messageDocument(msg *tg.Message) (domain.Document, bool) {
+	media, ok := msg.Media.(*tg.MessageMediaDocument)
+	if !ok {
+		return domain.Document{}, false
+	}
+	doc, ok := media.Document.(*tg.Document)
+	if !ok {
+		return domain.Document{}, false
+	}
+	return domain.Document{ID: doc.ID, AccessHash: doc.AccessHash, FileReference: doc.FileReference}, true
+}
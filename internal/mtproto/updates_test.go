@@ -0,0 +1,34 @@
+package mtproto
+
+import (
+	"testing"
+
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+func TestSentMessage(t *testing.T) {
+	doc := &tg.Document{ID: 1, AccessHash: 2, FileReference: []byte{3}}
+	upd := &tg.Updates{Updates: []tg.UpdateClass{
+		&tg.UpdateMessageID{ID: 10},
+		&tg.UpdateNewChannelMessage{Message: &tg.Message{
+			ID:    10,
+			Media: &tg.MessageMediaDocument{Document: doc},
+		}},
+	}}
+
+	msg, ok := sentMessage(upd)
+	require.True(t, ok)
+	require.Equal(t, 10, msg.ID)
+
+	d, ok := messageDocument(msg)
+	require.True(t, ok)
+	require.Equal(t, domain.Document{ID: 1, AccessHash: 2, FileReference: []byte{3}}, d)
+
+	_, ok = sentMessage(&tg.UpdatesTooLong{})
+	require.False(t, ok)
+
+	_, ok = messageDocument(&tg.Message{ID: 11})
+	require.False(t, ok)
+}
@@ -0,0 +1,247 @@
+// Package notify sends alerts about publishing problems to admin chat or webhook:
+// media moved to failed queue, media not sent to destinations, repeated session
+// restarts, long flood waits, and a daily summary. Alerts of the same kind are de-duplicated per bot.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+// Kind of alert.
+type Kind string
+
+const (
+	KindFailed    Kind = "failed"     // media moved to failed queue
+	KindMirror    Kind = "mirror"     // published media not sent to destination of topic
+	KindRestarts  Kind = "restarts"   // session restarted several times in a row
+	KindFloodWait Kind = "flood_wait" // flood wait longer than config.Notify.FloodWait
+	KindSummary   Kind = "summary"    // daily summary, never de-duplicated
+)
+
+const sendTimeout = 10 * time.Second
+
+// Alert is a notification about one event.
+type Alert struct {
+	Kind Kind   `json:"kind"`
+	Slug string `json:"slug,omitempty"` // slug of tg_config
+	Text string `json:"text"`
+}
+
+// Sender delivers alert to admins.
+type Sender interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// stats are counters of bot since the last summary.
+type stats struct {
+	published  int
+	failed     int
+	mirror     int
+	floodWaits int
+	restarts   int
+}
+
+// Notifier sends alerts to all senders. Methods are safe for concurrent use,
+// alerts are sent in background to not block publishing.
+type Notifier struct {
+	cfg     config.Notify
+	senders []Sender
+	now     func() time.Time
+
+	mu         sync.Mutex
+	sent       map[string]time.Time // last alert by kind and slug
+	suppressed map[string]int       // number of de-duplicated alerts since the last sent one
+	restarts   map[string]int       // session restarts in a row by slug
+	stats      map[string]*stats
+	wg         sync.WaitGroup
+}
+
+// New returns notifier which sends alerts to senders. Without senders alerts are dropped.
+func New(cfg config.Notify, senders ...Sender) *Notifier {
+	return &Notifier{
+		cfg:        cfg,
+		senders:    senders,
+		now:        time.Now,
+		sent:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+		restarts:   make(map[string]int),
+		stats:      make(map[string]*stats),
+	}
+}
+
+// Failed reports media moved to failed queue.
+func (n *Notifier) Failed(slug, title string, err error) {
+	n.mu.Lock()
+	n.stat(slug).failed++
+	n.mu.Unlock()
+
+	n.notify(KindFailed, slug, fmt.Sprintf("%s: '%s' moved to failed queue: %s", slug, title, err))
+}
+
+// MirrorFailed reports media published to topic but not sent to its destination.
+// Media isn't moved to failed queue, it would be published to topic again.
+func (n *Notifier) MirrorFailed(slug, destination, title string, err error) {
+	n.mu.Lock()
+	n.stat(slug).mirror++
+	n.mu.Unlock()
+
+	n.notify(KindMirror, slug, fmt.Sprintf("%s: '%s' not sent to destination %s: %s", slug, title, destination, err))
+}
+
+// Published resets counter of session restarts.
+func (n *Notifier) Published(slug string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stat(slug).published++
+	n.restarts[slug] = 0
+}
+
+// SessionRestarted reports session restart if it's restarted config.Notify.SessionRestarts
+// times in a row without publishing anything.
+func (n *Notifier) SessionRestarted(slug string, err error) {
+	n.mu.Lock()
+	n.stat(slug).restarts++
+	n.restarts[slug]++
+	count := n.restarts[slug]
+	n.mu.Unlock()
+
+	if n.cfg.SessionRestarts > 0 && count >= n.cfg.SessionRestarts {
+		n.notify(KindRestarts, slug, fmt.Sprintf("%s: telegram session restarted %d times in a row: %s", slug, count, err))
+	}
+}
+
+// FloodWait reports flood wait longer than config.Notify.FloodWait.
+func (n *Notifier) FloodWait(slug string, wait time.Duration) {
+	n.mu.Lock()
+	n.stat(slug).floodWaits++
+	n.mu.Unlock()
+
+	if wait >= n.cfg.FloodWait {
+		n.notify(KindFloodWait, slug, fmt.Sprintf("%s: FLOOD_WAIT for %s, publishing is paused", slug, wait.Round(time.Second)))
+	}
+}
+
+// Summary sends counters of every bot since the previous summary and their queue sizes.
+// Queue sizes can be nil if they are unknown.
+func (n *Notifier) Summary(queues map[string]domain.QueueStats) {
+	n.mu.Lock()
+	seen := make(map[string]bool)
+	var slugs []string
+	for slug := range queues {
+		seen[slug] = true
+		slugs = append(slugs, slug)
+	}
+	for slug := range n.stats {
+		if !seen[slug] {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+
+	var b strings.Builder
+	b.WriteString("Daily summary")
+	for _, slug := range slugs {
+		s, q := n.stat(slug), queues[slug]
+		fmt.Fprintf(&b, "\n%s: published %d, failed %d, not mirrored %d, flood waits %d, session restarts %d, queue %d, failed queue %d",
+			slug, s.published, s.failed, s.mirror, s.floodWaits, s.restarts, q.Queue, q.Failed)
+	}
+	n.stats = make(map[string]*stats)
+	n.mu.Unlock()
+
+	n.send(Alert{Kind: KindSummary, Text: b.String()})
+}
+
+// RunSummary sends summary every day at config.Notify.SummaryAt until ctx is canceled.
+// Queue sizes of bots are returned by queues.
+func (n *Notifier) RunSummary(ctx context.Context, queues func(ctx context.Context) (map[string]domain.QueueStats, error)) {
+	if n.cfg.SummaryAt == "" {
+		return
+	}
+	at, err := time.Parse("15:04", n.cfg.SummaryAt)
+	if err != nil {
+		log.Error().Err(err).Msg("daily summary is disabled")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(nextDaily(n.now(), at))):
+			q, err := queues(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("get queue sizes for daily summary")
+			}
+			n.Summary(q)
+		}
+	}
+}
+
+// Wait waits until all alerts are sent.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// nextDaily returns the next time after now at hour and minute of at.
+func nextDaily(now, at time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// stat must be called with mu locked.
+func (n *Notifier) stat(slug string) *stats {
+	s, ok := n.stats[slug]
+	if !ok {
+		s = &stats{}
+		n.stats[slug] = s
+	}
+	return s
+}
+
+// notify sends alert unless alert of the same kind and bot was sent within config.Notify.Dedup.
+// Number of suppressed alerts is added to the next sent one.
+func (n *Notifier) notify(kind Kind, slug, text string) {
+	key := string(kind) + "/" + slug
+	now := n.now()
+
+	n.mu.Lock()
+	if last, ok := n.sent[key]; ok && now.Sub(last) < n.cfg.Dedup {
+		n.suppressed[key]++
+		n.mu.Unlock()
+		return
+	}
+	if s := n.suppressed[key]; s > 0 {
+		text = fmt.Sprintf("%s (%d similar alerts suppressed)", text, s)
+	}
+	n.sent[key] = now
+	n.suppressed[key] = 0
+	n.mu.Unlock()
+
+	n.send(Alert{Kind: kind, Slug: slug, Text: text})
+}
+
+func (n *Notifier) send(a Alert) {
+	for _, s := range n.senders {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := s.Send(ctx, a); err != nil {
+				log.Error().Err(err).Str("kind", string(a.Kind)).Str("slug", a.Slug).Msg("send alert")
+			}
+		}()
+	}
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/config"
+	"gitlab.com/bvgm/tg/internal/domain"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Send(_ context.Context, a Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func testNotifier(cfg config.Notify) (*Notifier, *recorder, *time.Time) {
+	r := &recorder{}
+	n := New(cfg, r)
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	return n, r, &now
+}
+
+func TestNotifier_Dedup(t *testing.T) {
+	n, r, now := testNotifier(config.Notify{Dedup: time.Hour})
+
+	// alerts are sent in background, wait to keep order
+	n.Failed("goswami.ru", "Lecture 1", errors.New("file not found"))
+	n.Wait()
+	n.Failed("goswami.ru", "Lecture 2", errors.New("file not found"))
+	n.Failed("other", "Lecture 3", errors.New("file not found"))
+	n.Wait()
+	*now = now.Add(time.Hour)
+	n.Failed("goswami.ru", "Lecture 4", errors.New("file not found"))
+	n.Wait()
+
+	require.Len(t, r.alerts, 3)
+	require.Equal(t, "goswami.ru: 'Lecture 4' moved to failed queue: file not found (1 similar alerts suppressed)", r.alerts[2].Text)
+}
+
+func TestNotifier_SessionRestarted(t *testing.T) {
+	n, r, _ := testNotifier(config.Notify{SessionRestarts: 3})
+
+	err := errors.New("connection reset")
+	n.SessionRestarted("goswami.ru", err)
+	n.SessionRestarted("goswami.ru", err)
+	n.Published("goswami.ru")
+	n.SessionRestarted("goswami.ru", err)
+	n.SessionRestarted("goswami.ru", err)
+	n.Wait()
+	require.Empty(t, r.alerts)
+
+	n.SessionRestarted("goswami.ru", err)
+	n.Wait()
+	require.Len(t, r.alerts, 1)
+	require.Equal(t, KindRestarts, r.alerts[0].Kind)
+}
+
+func TestNotifier_FloodWait(t *testing.T) {
+	n, r, _ := testNotifier(config.Notify{FloodWait: 10 * time.Minute})
+
+	n.FloodWait("goswami.ru", time.Minute)
+	n.FloodWait("goswami.ru", time.Hour)
+	n.Wait()
+	require.Len(t, r.alerts, 1)
+	require.Equal(t, "goswami.ru: FLOOD_WAIT for 1h0m0s, publishing is paused", r.alerts[0].Text)
+}
+
+func TestNotifier_Summary(t *testing.T) {
+	n, r, _ := testNotifier(config.Notify{})
+
+	n.Published("goswami.ru")
+	n.Published("goswami.ru")
+	n.Failed("goswami.ru", "Lecture", errors.New("file not found"))
+	n.MirrorFailed("goswami.ru", "Channel", "Lecture", errors.New("CHAT_WRITE_FORBIDDEN"))
+	n.Wait()
+	n.Summary(map[string]domain.QueueStats{"goswami.ru": {Queue: 10, Failed: 1}})
+	n.Wait()
+
+	require.Len(t, r.alerts, 3)
+	require.Equal(t, "Daily summary\ngoswami.ru: published 2, failed 1, not mirrored 1, flood waits 0, session restarts 0, queue 10, failed queue 1", r.alerts[2].Text)
+
+	// counters are reset
+	n.Summary(nil)
+	n.Wait()
+	require.Equal(t, "Daily summary", r.alerts[3].Text)
+}
+
+func TestNextDaily(t *testing.T) {
+	at, _ := time.Parse("15:04", "09:00")
+	now := time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC), nextDaily(now, at))
+	require.Equal(t, time.Date(2025, 9, 2, 9, 0, 0, 0, time.UTC), nextDaily(now.Add(time.Hour), at))
+}
+
+func TestWebhook(t *testing.T) {
+	var got Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	a := Alert{Kind: KindFailed, Slug: "goswami.ru", Text: "failed"}
+	require.NoError(t, NewWebhook(srv.URL, nil).Send(context.Background(), a))
+	require.Equal(t, a, got)
+}
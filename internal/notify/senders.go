@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/bvgm/tg/internal/tgapi"
+)
+
+// Chat sends alerts to telegram chat with bot API.
+type Chat struct {
+	bot    tgapi.TelegramPublisher
+	chatID int64
+}
+
+func NewChat(token string, chatID int64, client *http.Client) *Chat {
+	return &Chat{bot: tgapi.New(token, chatID, nil, client), chatID: chatID}
+}
+
+func (c *Chat) Send(ctx context.Context, a Alert) error {
+	return c.bot.SendMessage(ctx, c.chatID, a.Text)
+}
+
+// Webhook posts alerts as JSON, see Alert.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns sender of alerts to url. http.DefaultClient is used if client is nil.
+func NewWebhook(url string, client *http.Client) *Webhook {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Webhook{url: url, client: client}
+}
+
+func (w *Webhook) Send(ctx context.Context, a Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("post webhook: %s", res.Status)
+	}
+	return nil
+}
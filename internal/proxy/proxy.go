@@ -0,0 +1,146 @@
+// Package proxy connects to Telegram through SOCKS5 or HTTP proxy and MTProxy:
+// dialer of MTProto connections and http client of bot API.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/telegram/dcs"
+	"gitlab.com/bvgm/tg/internal/config"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// Resolver returns resolver of DCs for telegram.Options, nil if proxy isn't set
+// and default resolver of gotd is used.
+func Resolver(c config.Proxy) (dcs.Resolver, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	dial, err := Dial(c)
+	if err != nil {
+		return nil, err
+	}
+	if c.MTProxy == "" {
+		return dcs.Plain(dcs.PlainOptions{Dial: dial}), nil
+	}
+
+	secret, err := c.ParseSecret()
+	if err != nil {
+		return nil, fmt.Errorf("mtproxy secret: %w", err)
+	}
+	r, err := dcs.MTProxy(c.MTProxy, secret, dcs.MTProxyOptions{Dial: dial})
+	if err != nil {
+		return nil, fmt.Errorf("create mtproxy resolver: %w", err)
+	}
+	return r, nil
+}
+
+// Dial returns function which connects through proxy of URL, direct connections are made if URL is empty.
+func Dial(c config.Proxy) (dcs.DialFunc, error) {
+	var direct net.Dialer
+	if c.URL == "" {
+		return direct.DialContext, nil
+	}
+	u, err := c.ParseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "http" {
+		return httpConnect(u, direct.DialContext), nil
+	}
+
+	var auth *xproxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &xproxy.Auth{User: u.User.Username(), Password: password}
+	}
+	d, err := xproxy.SOCKS5("tcp", u.Host, auth, &direct)
+	if err != nil {
+		return nil, fmt.Errorf("create socks5 dialer: %w", err)
+	}
+	return d.(xproxy.ContextDialer).DialContext, nil
+}
+
+// HTTPClient returns client of bot API, http.DefaultClient if URL is empty.
+// MTProxy isn't used, it proxies MTProto only.
+func HTTPClient(c config.Proxy) (*http.Client, error) {
+	if c.URL == "" {
+		return http.DefaultClient, nil
+	}
+	u, err := c.ParseURL()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyURL(u)
+	return &http.Client{Transport: t}, nil
+}
+
+// httpConnect returns function which opens tunnel with CONNECT request to HTTP proxy.
+func httpConnect(u *url.URL, dial dcs.DialFunc) dcs.DialFunc {
+	return func(ctx context.Context, network, addr string) (_ net.Conn, rerr error) {
+		conn, err := dial(ctx, network, u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("dial http proxy: %w", err)
+		}
+		defer func() {
+			if rerr != nil {
+				_ = conn.Close()
+			}
+		}()
+		if deadline, ok := ctx.Deadline(); ok {
+			if err := conn.SetDeadline(deadline); err != nil {
+				return nil, err
+			}
+			defer func() { _ = conn.SetDeadline(time.Time{}) }()
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if u.User != nil {
+			password, _ := u.User.Password()
+			creds := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+creds)
+		}
+		if err := req.Write(conn); err != nil {
+			return nil, fmt.Errorf("write CONNECT request: %w", err)
+		}
+
+		br := bufio.NewReader(conn)
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			return nil, fmt.Errorf("read CONNECT response: %w", err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("http proxy: %s", strings.TrimSpace(res.Status))
+		}
+		if br.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+		return conn, nil
+	}
+}
+
+// bufferedConn reads data received with response of proxy first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
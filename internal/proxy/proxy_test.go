@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/config"
+)
+
+func TestResolver(t *testing.T) {
+	r, err := Resolver(config.Proxy{})
+	require.NoError(t, err)
+	require.Nil(t, r) // default resolver of gotd
+
+	r, err = Resolver(config.Proxy{URL: "socks5://127.0.0.1:1080"})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+
+	r, err = Resolver(config.Proxy{MTProxy: "127.0.0.1:443", Secret: "dd00112233445566778899aabbccddeeff"})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+}
+
+func TestDial_HTTPConnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	got := make(chan *http.Request, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		got <- req
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\npong")
+	}()
+
+	dial, err := Dial(config.Proxy{URL: "http://user:secret@" + l.Addr().String()})
+	require.NoError(t, err)
+	conn, err := dial(context.Background(), "tcp", "149.154.167.50:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := <-got
+	require.Equal(t, http.MethodConnect, req.Method)
+	require.Equal(t, "149.154.167.50:443", req.Host)
+	require.Equal(t, "Basic dXNlcjpzZWNyZXQ=", req.Header.Get("Proxy-Authorization"))
+
+	b := make([]byte, 4)
+	_, err = io.ReadFull(conn, b)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(b)) // received with response of proxy
+}
+
+func TestHTTPClient(t *testing.T) {
+	c, err := HTTPClient(config.Proxy{})
+	require.NoError(t, err)
+	require.Same(t, http.DefaultClient, c)
+
+	c, err = HTTPClient(config.Proxy{URL: "socks5://127.0.0.1:1080"})
+	require.NoError(t, err)
+	u, err := c.Transport.(*http.Transport).Proxy(&http.Request{})
+	require.NoError(t, err)
+	require.Equal(t, "socks5://127.0.0.1:1080", u.String())
+}
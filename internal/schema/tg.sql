@@ -1,24 +1,41 @@
+create table tg_config (
+    id bigserial primary key,
+    slug text unique not null,
+    recent_upload_time timestamp not null,
+    settings jsonb not null,
+    paused boolean not null default false
+);
+COMMENT ON TABLE tg_config IS 'Config for sending messages. Settings are loaded by slug, see tg start --slug';
+COMMENT ON COLUMN tg_config.slug IS 'Unique slug for config. Used for getting config by slug';
+COMMENT ON COLUMN tg_config.recent_upload_time IS 'Last time updated topics for telegram, updated when recent audio sent to topic.';
+COMMENT ON COLUMN tg_config.settings IS 'Bot settings for sending messages.';
+COMMENT ON COLUMN tg_config.paused IS 'Publishing of all topics of config is paused, see tg pause';
+
+-- pause of all bots, one row at most
+create table tg_pause (
+    id boolean primary key default true check (id),
+    created timestamp not null default now()
+);
+COMMENT ON TABLE tg_pause IS 'Publishing of all topics is paused while table has row, topics without config too, see tg pause without --slug';
+
 -- topics in tg group
 create table tg_topics (
     id bigserial primary key,
     message_thread_id bigint not null,
     tag_id integer references tag(id) not null,
-    name text unique not null,
+    name text not null,
     icon_custom_emoji_id varchar(128),
     created timestamp default NULL,
-    CONSTRAINT tg_unique_topic UNIQUE(message_thread_id, tag_id)
+    delivery jsonb not null default '{}'::jsonb,
+    config_id bigint references tg_config(id),
+    paused boolean not null default false,
+    CONSTRAINT tg_unique_topic UNIQUE(config_id, message_thread_id, tag_id),
+    CONSTRAINT tg_unique_topic_name UNIQUE(config_id, name)
 );
+COMMENT ON COLUMN tg_topics.delivery IS 'Delivery policy of topic, overrides global one. Example: {"timezone": "Europe/Moscow", "drip": {"posts_per_hour": 2, "window": "09:00-21:00", "silent": true}, "quiet": {"window": "22:00-08:00", "mode": "silent"}}';
+COMMENT ON COLUMN tg_topics.config_id IS 'Config (bot and group) of topic. Queue of topic is published by this config, see tg start --all';
+COMMENT ON COLUMN tg_topics.paused IS 'Publishing of topic is paused, see tg pause --topic';
 
-create table tg_config (
-    id bigserial primary key,
-    slug text unique not null,
-    recent_upload_time timestamp not null,
-    settings jsonb not null
-);
-COMMENT ON TABLE tg_config IS 'Config for sending messages. For future use when will be many chatbots on one server';
-COMMENT ON COLUMN tg_config.slug IS 'Unique slug for config. Used for getting config by slug';
-COMMENT ON COLUMN tg_config.recent_upload_time IS 'Last time updated topics for telegram, updated when recent audio sent to topic.';
-COMMENT ON COLUMN tg_config.settings IS 'Bot settings for sending messages.';
 
 -- function to fill tg_queue on inserting data into media_tag
 create or replace function copy_media_tag_to_queue() returns trigger
@@ -28,12 +45,17 @@ AS $copy_media_tag_to_queue$
             select t.id from tg_topics t where t.tag_id = new.tag_id
         )
         insert into tg_queue  
-            (topic_id, media_id, tag_id)
+            (topic_id, media_id, tag_id, priority)
         select
             topic.id,
             NEW.media_id,
-            NEW.tag_id
-        from topic;
+            NEW.tag_id,
+            -- priority of fresh media, must be equal to domain.PriorityFresh
+            100
+        from topic
+        -- media may be already queued by populate, raise it to the fresh lane
+        on conflict (topic_id, media_id) do update
+            set priority = greatest(tg_queue.priority, excluded.priority);
         return NEW;
     end;
 $copy_media_tag_to_queue$ language plpgsql;
@@ -48,19 +70,75 @@ create table tg_queue (
     id bigserial primary key,
     topic_id bigint references tg_topics(id) not null,
     media_id integer references media(id) not null,
-    tag_id integer references tag(id) not null
+    tag_id integer references tag(id) not null,
+    priority integer not null default 0,
+    created timestamp not null default now()
 );
 create unique index tg_queue_unique_idx on tg_queue (topic_id, media_id);
+COMMENT ON COLUMN tg_queue.priority IS 'Media with higher priority are published first. Fresh media inserted by trigger have 100 (domain.PriorityFresh), populated archive has 0 by default.';
+COMMENT ON COLUMN tg_queue.created IS 'Media is queued, message of media posted before is not forwarded to topic, see telegram.multi_tag';
 
 create table tg_queue_failed (
     id bigserial primary key,
     topic_id bigint references tg_topics(id) not null,
     media_id integer references media(id) not null,
     tag_id integer references tag(id) not null,
-    error text not null
+    error text not null,
+    queued timestamp
 );
+COMMENT ON COLUMN tg_queue_failed.queued IS 'Media was queued, it is queued with the same time again by tg queue retry-failed';
 create unique index tg_queue_failed_unique_idx on tg_queue (topic_id, media_id);
 
+-- messages with media published to topics
+create table tg_messages (
+    id bigserial primary key,
+    topic_id bigint references tg_topics(id) not null,
+    media_id integer references media(id) not null,
+    message_id integer not null,
+    document_id bigint not null,
+    access_hash bigint not null,
+    file_reference bytea not null,
+    created timestamp not null default now(),
+    origin_id bigint references tg_messages(id)
+);
+create index tg_messages_media_idx on tg_messages (media_id);
+COMMENT ON TABLE tg_messages IS 'Published messages with media. Document is sent again to answer commands of listeners without uploading file.';
+COMMENT ON COLUMN tg_messages.message_id IS 'ID of message in group of topic';
+COMMENT ON COLUMN tg_messages.origin_id IS 'Message forwarded or sent again to topic if media is posted once, see telegram.multi_tag. Null - media was posted';
+
+-- subscriptions of listeners to tags, new media are sent to them by direct message
+create table tg_subscriptions (
+    id bigserial primary key,
+    config_id bigint references tg_config(id),
+    user_id bigint not null,
+    access_hash bigint not null,
+    tag_id integer references tag(id) on delete cascade not null,
+    created timestamp not null default now()
+);
+create unique index tg_subscriptions_unique_idx on tg_subscriptions (coalesce(config_id, 0), user_id, tag_id);
+create index tg_subscriptions_tag_idx on tg_subscriptions (tag_id);
+COMMENT ON TABLE tg_subscriptions IS 'Subscriptions of listeners to tags, see bot commands /subscribe and /unsubscribe.';
+COMMENT ON COLUMN tg_subscriptions.config_id IS 'Config (bot) which sends media to subscriber';
+COMMENT ON COLUMN tg_subscriptions.access_hash IS 'Access hash of user for the bot, it is required to send direct message';
+
+-- additional chats where media of topic is published: channels, groups and topics of other forum groups
+create table tg_destinations (
+    id bigserial primary key,
+    topic_id bigint references tg_topics(id) on delete cascade not null,
+    name text not null,
+    channel_id bigint not null,
+    access_hash bigint not null,
+    message_thread_id integer,
+    caption_template text,
+    created timestamp not null default now()
+);
+create unique index tg_destinations_unique_idx on tg_destinations (topic_id, channel_id, coalesce(message_thread_id, 0));
+COMMENT ON TABLE tg_destinations IS 'Mirrors of topic. Document uploaded to topic is sent to each destination without uploading file again, see tg topics destinations';
+COMMENT ON COLUMN tg_destinations.channel_id IS 'MTProto ID of channel or supergroup, without -100 prefix';
+COMMENT ON COLUMN tg_destinations.access_hash IS 'Access hash of channel for the bot';
+COMMENT ON COLUMN tg_destinations.message_thread_id IS 'Topic of forum group, null for channel or group without topics';
+COMMENT ON COLUMN tg_destinations.caption_template IS 'Caption template of destination, null - caption_template of config';
+
 -- tables from main schema (DO NOT CREATE IT) it's for sqlc only
 
 CREATE TABLE tag (
@@ -112,6 +190,60 @@ CREATE INDEX media_tag_media_idx ON public.media_tag USING btree (media_id);
 CREATE INDEX media_tag_tag_idx ON public.media_tag USING btree (tag_id);
 COMMENT ON TABLE public.media_tag IS 'Связка лекции и ключевых слов';
 
+-- media which tg populate adds to queue of their topics, filter is not used if its argument is null or empty
+create or replace function tg_populate_candidates(
+    only_config_id bigint,
+    since timestamp,
+    until timestamp,
+    only_tag_id integer,
+    only_topic_id bigint,
+    only_media_ids integer[],
+    skip_published boolean
+) returns table (
+    topic_id bigint,
+    topic text,
+    media_id integer,
+    title varchar,
+    file_url text,
+    occurrence_date date,
+    tag_id integer,
+    tag varchar,
+    published boolean
+)
+AS $tg_populate_candidates$
+    select
+        tt.id,
+        tt.name,
+        m.id,
+        m.title,
+        m.file_url,
+        m.occurrence_date,
+        t.id,
+        t.name,
+        exists (
+            select 1 from media_data md
+            where md.media_id = m.id and md.data_type = 'telegram'::media_data_type
+        )
+    from media m
+    join media_tag mt on m.id = mt.media_id
+    join tag t on t.id = mt.tag_id
+    join tg_topics tt on tt.tag_id = mt.tag_id
+    left join tg_queue tq on tq.media_id = m.id and tq.topic_id = tt.id
+    where
+        m.file_url is not null
+        and tq.id is null
+        and (only_config_id is null or tt.config_id = only_config_id)
+        and (since is null or m.occurrence_date > since)
+        and (until is null or m.occurrence_date <= until)
+        and (only_tag_id is null or t.id = only_tag_id)
+        and (only_topic_id is null or tt.id = only_topic_id)
+        and (cardinality(only_media_ids) = 0 or m.id = any(only_media_ids))
+        and (not skip_published or not exists (
+            select 1 from media_data md
+            where md.media_id = m.id and md.data_type = 'telegram'::media_data_type
+        ));
+$tg_populate_candidates$ language sql stable;
+
 
 -------- START MIGRATION ---------
 
@@ -119,6 +251,26 @@ COMMENT ON TABLE public.media_tag IS 'Связка лекции и ключев
 -- ALTER TABLE media_data ALTER COLUMN media_id SET NOT NULL;
 -- ALTER TABLE media_data ALTER COLUMN value SET NOT NULL;
 -- ALTER TABLE media_data ALTER COLUMN data_type SET NOT NULL;
+-- ALTER TABLE tg_topics ADD COLUMN delivery jsonb not null default '{}'::jsonb;
+-- ALTER TABLE tg_queue ADD COLUMN priority integer not null default 0;
+-- recreate function copy_media_tag_to_queue() to set priority of fresh media
+-- create function tg_populate_candidates()
+-- recreate function copy_media_tag_to_queue() to raise priority of media queued by populate
+-- ALTER TABLE tg_topics ADD COLUMN config_id bigint references tg_config(id);
+-- UPDATE tg_topics SET config_id = (select id from tg_config where slug = 'goswami.ru');
+-- ALTER TABLE tg_topics DROP CONSTRAINT tg_unique_topic, DROP CONSTRAINT tg_topics_name_key;
+-- ALTER TABLE tg_topics ADD CONSTRAINT tg_unique_topic UNIQUE(config_id, message_thread_id, tag_id);
+-- ALTER TABLE tg_topics ADD CONSTRAINT tg_unique_topic_name UNIQUE(config_id, name);
+-- create table tg_messages and index tg_messages_media_idx
+-- DROP FUNCTION tg_populate_candidates(timestamp, timestamp, integer, bigint, integer[], boolean); then create it again with only_config_id
+-- ALTER TABLE tg_config ADD COLUMN paused boolean not null default false;
+-- ALTER TABLE tg_topics ADD COLUMN paused boolean not null default false;
+-- create table tg_pause
+-- create table tg_subscriptions and indexes tg_subscriptions_unique_idx, tg_subscriptions_tag_idx
+-- create table tg_destinations and index tg_destinations_unique_idx
+-- ALTER TABLE tg_messages ADD COLUMN origin_id bigint references tg_messages(id);
+-- ALTER TABLE tg_queue ADD COLUMN created timestamp not null default now();
+-- ALTER TABLE tg_queue_failed ADD COLUMN queued timestamp;
 
 -- insert into
 -- tg_config (slug, recent_upload_time, settings)
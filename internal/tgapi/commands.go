@@ -1,7 +1,7 @@
 package tgapi
 
 type createForumTopic struct {
-	ChatID  int     `json:"chat_id"`
+	ChatID  int64   `json:"chat_id"`
 	Name    string  `json:"name"`
 	EmojiID *string `json:"icon_custom_emoji_id,omitempty"`
 }
@@ -23,3 +23,8 @@ type createForumTopicResponseResult struct {
 	ErrCode int    `json:"error_code"`
 	Message string `json:"description"`
 }
+
+type sendMessage struct {
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
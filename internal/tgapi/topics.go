@@ -2,26 +2,34 @@ package tgapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 
-	"github.com/spf13/viper"
 	"gitlab.com/bvgm/tg/internal/domain"
 )
 
 type TelegramPublisher struct {
-	list   []domain.Topic
-	config *viper.Viper
+	list    []domain.Topic
+	token   string // bot token
+	groupID int64  // chat ID of group, e.g. -1001234567890
+	client  *http.Client
 }
 
-func New(top []domain.Topic) TelegramPublisher {
-
+// New returns client of bot API. http.DefaultClient is used if client is nil,
+// see proxy.HTTPClient.
+func New(token string, groupID int64, top []domain.Topic, client *http.Client) TelegramPublisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
 	return TelegramPublisher{
-		config: viper.GetViper(),
-		list:   top,
+		token:   token,
+		groupID: groupID,
+		list:    top,
+		client:  client,
 	}
 }
 
@@ -30,11 +38,11 @@ func (t *TelegramPublisher) CreateGroupTopic(topic domain.Topic) (*forumTopic, e
 	u := url.URL{
 		Scheme: "https",
 		Host:   "api.telegram.org",
-		Path:   fmt.Sprintf("/bot%v/createForumTopic", t.config.GetString("telegram.bot_token")),
+		Path:   fmt.Sprintf("/bot%v/createForumTopic", t.token),
 	}
 
 	jb, err := json.Marshal(createForumTopic{
-		ChatID:  t.config.GetInt("telegram.group_id"),
+		ChatID:  t.groupID,
 		Name:    topic.Name,
 		EmojiID: topic.IconCustomEmojiID,
 	})
@@ -46,7 +54,7 @@ func (t *TelegramPublisher) CreateGroupTopic(topic domain.Topic) (*forumTopic, e
 	defer req.Body.Close()
 
 	req.Header.Set("Content-Type", "application/json")
-	res, err := http.DefaultClient.Do(req)
+	res, err := t.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("create forum topic: %w", err)
 	}
@@ -71,3 +79,43 @@ func (t *TelegramPublisher) CreateGroupTopic(topic domain.Topic) (*forumTopic, e
 	}
 	return &response.Topic, nil
 }
+
+// SendMessage sends text message to chat, e.g. to admin chat.
+func (t *TelegramPublisher) SendMessage(ctx context.Context, chatID int64, text string) error {
+	u := url.URL{
+		Scheme: "https",
+		Host:   "api.telegram.org",
+		Path:   fmt.Sprintf("/bot%v/sendMessage", t.token),
+	}
+
+	jb, err := json.Marshal(sendMessage{ChatID: chatID, Text: text})
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewBuffer(jb))
+	if err != nil {
+		return fmt.Errorf("create http request to send message: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	res, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("read http response: %w", err)
+	}
+
+	var status createForumTopicResponseResult
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("JSON parse status: %w", err)
+	}
+	if !status.Ok {
+		return fmt.Errorf("send message: %s (%d)", status.Message, status.ErrCode)
+	}
+	return nil
+}
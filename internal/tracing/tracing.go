@@ -0,0 +1,78 @@
+// Package tracing sets up OpenTelemetry tracing of publishing: queue fetch,
+// database queries, upload and send of media, and MTProto rpc calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gitlab.com/bvgm/tg/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "tg"
+
+// Setup registers global tracer provider with exporter from config.
+// Returned function flushes spans and must be called before exit.
+// Nothing is set up if exporter is empty, tracers of otel are no-op then.
+func Setup(ctx context.Context, c config.Tracing) (func(context.Context) error, error) {
+	if c.Exporter == config.ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", c.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, c config.Tracing) (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case config.ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case config.ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", c.Exporter)
+	}
+}
+
+// Fail marks span as failed with err.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/bvgm/tg/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	shutdown, err := Setup(ctx, config.Tracing{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+
+	shutdown, err = Setup(ctx, config.Tracing{Exporter: config.ExporterStdout, SampleRatio: 1})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+}